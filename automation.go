@@ -0,0 +1,82 @@
+package vst2
+
+import (
+	"math"
+	"sort"
+)
+
+// AutomationCurve defines how parameter value changes between two
+// breakpoints.
+type AutomationCurve int
+
+const (
+	// CurveLinear changes value linearly.
+	CurveLinear AutomationCurve = iota
+	// CurveStep keeps value until the next breakpoint.
+	CurveStep
+	// CurveExponential changes value exponentially, which is linear in
+	// logarithmic scale. It falls back to linear if any of values is not
+	// positive.
+	CurveExponential
+)
+
+type (
+	// Breakpoint is a parameter value at certain sample position. Curve
+	// defines the shape of segment that starts at this breakpoint.
+	Breakpoint struct {
+		Pos   int64
+		Value float32
+		Curve AutomationCurve
+	}
+
+	// AutomationLane contains breakpoints of a single parameter. Points
+	// must be sorted by position.
+	AutomationLane struct {
+		Index  int
+		Points []Breakpoint
+	}
+)
+
+// ValueAt returns parameter value at sample position. Before the first
+// breakpoint its value is used, after the last one the last value is
+// kept. False is returned if lane has no breakpoints.
+func (l AutomationLane) ValueAt(pos int64) (float32, bool) {
+	if len(l.Points) == 0 {
+		return 0, false
+	}
+	// index of the first breakpoint after position.
+	i := sort.Search(len(l.Points), func(i int) bool {
+		return l.Points[i].Pos > pos
+	})
+	if i == 0 {
+		return l.Points[0].Value, true
+	}
+	if i == len(l.Points) {
+		return l.Points[i-1].Value, true
+	}
+	return interpolate(l.Points[i-1], l.Points[i], pos), true
+}
+
+// interpolate calculates value between two breakpoints.
+func interpolate(from, to Breakpoint, pos int64) float32 {
+	t := float64(pos-from.Pos) / float64(to.Pos-from.Pos)
+	v0, v1 := float64(from.Value), float64(to.Value)
+	switch from.Curve {
+	case CurveStep:
+		return from.Value
+	case CurveExponential:
+		if v0 > 0 && v1 > 0 {
+			return float32(v0 * math.Pow(v1/v0, t))
+		}
+	}
+	return float32(v0 + (v1-v0)*t)
+}
+
+// automate sets parameter values of lanes at current position.
+func (p *Processor) automate() {
+	for _, l := range p.Automation {
+		if v, ok := l.ValueAt(p.currentPosition); ok {
+			p.plugin.SetParameter(l.Index, v)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package vst2_test
+
+import (
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutomationLane(t *testing.T) {
+	tests := []struct {
+		lane     vst2.AutomationLane
+		pos      int64
+		expected float32
+		ok       bool
+	}{
+		{
+			lane: vst2.AutomationLane{},
+			pos:  10,
+		},
+		{
+			lane: vst2.AutomationLane{
+				Points: []vst2.Breakpoint{{Pos: 10, Value: 0.5}},
+			},
+			pos:      0,
+			expected: 0.5,
+			ok:       true,
+		},
+		{
+			lane: vst2.AutomationLane{
+				Points: []vst2.Breakpoint{{Pos: 0, Value: 0}, {Pos: 100, Value: 1}},
+			},
+			pos:      25,
+			expected: 0.25,
+			ok:       true,
+		},
+		{
+			lane: vst2.AutomationLane{
+				Points: []vst2.Breakpoint{{Pos: 0, Value: 0, Curve: vst2.CurveStep}, {Pos: 100, Value: 1}},
+			},
+			pos:      99,
+			expected: 0,
+			ok:       true,
+		},
+		{
+			lane: vst2.AutomationLane{
+				Points: []vst2.Breakpoint{{Pos: 0, Value: 0.1, Curve: vst2.CurveExponential}, {Pos: 100, Value: 1}},
+			},
+			pos:      50,
+			expected: 0.31622776,
+			ok:       true,
+		},
+		{
+			lane: vst2.AutomationLane{
+				Points: []vst2.Breakpoint{{Pos: 0, Value: 0, Curve: vst2.CurveExponential}, {Pos: 100, Value: 1}},
+			},
+			pos:      50,
+			expected: 0.5,
+			ok:       true,
+		},
+		{
+			lane: vst2.AutomationLane{
+				Points: []vst2.Breakpoint{{Pos: 0, Value: 0}, {Pos: 100, Value: 1}},
+			},
+			pos:      200,
+			expected: 1,
+			ok:       true,
+		},
+	}
+	for _, test := range tests {
+		v, ok := test.lane.ValueAt(test.pos)
+		assert.Equal(t, test.ok, ok)
+		assert.InDelta(t, test.expected, v, 1e-6)
+	}
+}
+
+func TestAutomationRecorder(t *testing.T) {
+	tests := []struct {
+		mode     vst2.AutomationMode
+		state    vst2.AutomationState
+		expected []vst2.AutomationEvent
+	}{
+		{
+			mode:  vst2.AutomationRead,
+			state: vst2.AutomationStateRead,
+		},
+		{
+			mode:  vst2.AutomationWrite,
+			state: vst2.AutomationStateWrite,
+			expected: []vst2.AutomationEvent{
+				{Kind: vst2.AutomationValue, Index: 1, Value: 0.1, Pos: 0},
+				{Kind: vst2.AutomationBeginEdit, Index: 1, Pos: 10},
+				{Kind: vst2.AutomationValue, Index: 1, Value: 0.2, Pos: 20},
+				{Kind: vst2.AutomationEndEdit, Index: 1, Pos: 30},
+				{Kind: vst2.AutomationValue, Index: 1, Value: 0.3, Pos: 40},
+			},
+		},
+		{
+			mode:  vst2.AutomationTouch,
+			state: vst2.AutomationStateReadWrite,
+			expected: []vst2.AutomationEvent{
+				{Kind: vst2.AutomationBeginEdit, Index: 1, Pos: 10},
+				{Kind: vst2.AutomationValue, Index: 1, Value: 0.2, Pos: 20},
+				{Kind: vst2.AutomationEndEdit, Index: 1, Pos: 30},
+			},
+		},
+		{
+			mode:  vst2.AutomationLatch,
+			state: vst2.AutomationStateReadWrite,
+			expected: []vst2.AutomationEvent{
+				{Kind: vst2.AutomationBeginEdit, Index: 1, Pos: 10},
+				{Kind: vst2.AutomationValue, Index: 1, Value: 0.2, Pos: 20},
+				{Kind: vst2.AutomationEndEdit, Index: 1, Pos: 30},
+				{Kind: vst2.AutomationValue, Index: 1, Value: 0.3, Pos: 40},
+			},
+		},
+	}
+	for _, test := range tests {
+		r := vst2.NewAutomationRecorder(test.mode)
+		assert.Equal(t, test.state, r.State())
+		r.Automate(1, 0.1, 0)
+		r.BeginEdit(1, 10)
+		r.Automate(1, 0.2, 20)
+		r.EndEdit(1, 30)
+		r.Automate(1, 0.3, 40)
+		assert.Equal(t, test.expected, r.Events())
+	}
+}
+
+func TestProcessorAutomation(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	const granularity, size = 8, 48
+	lane := vst2.AutomationLane{
+		Index: 0,
+		Points: []vst2.Breakpoint{
+			{Pos: 0, Value: 0},
+			{Pos: 32, Value: 1},
+		},
+	}
+	p := vst2.Processor{
+		VST:                   v,
+		Automation:            []vst2.AutomationLane{lane},
+		AutomationGranularity: granularity,
+	}
+	fn, err := p.Process("", sampleRate, 2)
+	require.Nil(t, err)
+	defer p.Flush("")
+
+	// test plugin applies gain parameter, so output of constant input
+	// follows parameter value.
+	s := signal.Float64Buffer(2, size)
+	for i := range s[0] {
+		s[0][i], s[1][i] = 1, 1
+	}
+	require.Nil(t, fn(s))
+	for i, v := range s[0] {
+		// value is set at the start of every sub-block.
+		expected, _ := lane.ValueAt(int64(i / granularity * granularity))
+		assert.Equal(t, float64(expected), v, "sample %d", i)
+	}
+}
@@ -0,0 +1,154 @@
+package vst2_test
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+)
+
+var (
+	benchBlockSizes  = []int{64, 512, 4096}
+	benchNumChannels = []int{1, 2, 8}
+)
+
+func benchHostCallback() vst2.HostCallbackFunc {
+	return func(vst2.HostOpcode, vst2.Index, vst2.Value, vst2.Ptr, vst2.Opt) vst2.Return {
+		return 0
+	}
+}
+
+// loadBenchPlugin opens test plugin with provided callback and resumes
+// it. Returned function closes plugin and VST.
+func loadBenchPlugin(b *testing.B, c vst2.HostCallbackFunc) (*vst2.Plugin, func()) {
+	b.Helper()
+	if pluginPath == "" {
+		b.Skipf("no test plugin for %s", runtime.GOOS)
+	}
+	v, err := vst2.Open(pluginPath)
+	if err != nil {
+		b.Fatalf("failed to open plugin: %v", err)
+	}
+	p := v.Load(c)
+	p.SetSampleRate(sampleRate)
+	p.SetBufferSize(benchBlockSizes[len(benchBlockSizes)-1])
+	p.Start()
+	p.StartProcess()
+	return p, func() {
+		p.Close()
+		v.Close()
+	}
+}
+
+// numBenchChannels returns number of channels to allocate, buffers must
+// have at least as many channels as plugin has pins.
+func numBenchChannels(p *vst2.Plugin, numChannels int) int {
+	if n := len(p.InputPins()); n > numChannels {
+		numChannels = n
+	}
+	if n := len(p.OutputPins()); n > numChannels {
+		numChannels = n
+	}
+	return numChannels
+}
+
+func BenchmarkProcessDouble(b *testing.B) {
+	p, closeFn := loadBenchPlugin(b, benchHostCallback())
+	defer closeFn()
+	for _, numChannels := range benchNumChannels {
+		for _, blockSize := range benchBlockSizes {
+			b.Run(fmt.Sprintf("%dch/%d", numChannels, blockSize), func(b *testing.B) {
+				n := numBenchChannels(p, numChannels)
+				in := vst2.NewDoubleBuffer(n, blockSize)
+				out := vst2.NewDoubleBuffer(n, blockSize)
+				defer in.Free()
+				defer out.Free()
+				b.SetBytes(int64(8 * numChannels * blockSize))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					p.ProcessDouble(in, out)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkProcessFloat(b *testing.B) {
+	p, closeFn := loadBenchPlugin(b, benchHostCallback())
+	defer closeFn()
+	for _, numChannels := range benchNumChannels {
+		for _, blockSize := range benchBlockSizes {
+			b.Run(fmt.Sprintf("%dch/%d", numChannels, blockSize), func(b *testing.B) {
+				n := numBenchChannels(p, numChannels)
+				in := vst2.NewFloatBuffer(n, blockSize)
+				out := vst2.NewFloatBuffer(n, blockSize)
+				defer in.Free()
+				defer out.Free()
+				b.SetBytes(int64(4 * numChannels * blockSize))
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					p.ProcessFloat(in, out)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkDispatch measures host to plugin call.
+func BenchmarkDispatch(b *testing.B) {
+	p, closeFn := loadBenchPlugin(b, benchHostCallback())
+	defer closeFn()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Dispatch(vst2.EffGetVstVersion, 0, 0, nil, 0)
+	}
+}
+
+// BenchmarkHostCallback measures plugin to host call. Plugin is notified
+// about offline files and calls back with HostOfflineStart. Host to
+// plugin call is included, see BenchmarkDispatch for its cost.
+func BenchmarkHostCallback(b *testing.B) {
+	var calls int
+	p, closeFn := loadBenchPlugin(b, func(opcode vst2.HostOpcode, _ vst2.Index, _ vst2.Value, _ vst2.Ptr, _ vst2.Opt) vst2.Return {
+		if opcode == vst2.HostOfflineStart {
+			calls++
+		}
+		return 0
+	})
+	defer closeFn()
+	if p.Dispatch(vst2.EffOfflineNotify, 1, 0, nil, 0); calls == 0 {
+		b.Skip("plugin doesn't call host on offline notify")
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Dispatch(vst2.EffOfflineNotify, 1, 0, nil, 0)
+	}
+}
+
+func BenchmarkBufferCopy(b *testing.B) {
+	for _, numChannels := range benchNumChannels {
+		for _, blockSize := range benchBlockSizes {
+			s := signal.Float64Buffer(numChannels, blockSize)
+			b.Run(fmt.Sprintf("double/%dch/%d", numChannels, blockSize), func(b *testing.B) {
+				buf := vst2.NewDoubleBuffer(numChannels, blockSize)
+				defer buf.Free()
+				b.SetBytes(int64(8 * numChannels * blockSize))
+				for i := 0; i < b.N; i++ {
+					buf.CopyFrom(s)
+					buf.CopyTo(s)
+				}
+			})
+			b.Run(fmt.Sprintf("float/%dch/%d", numChannels, blockSize), func(b *testing.B) {
+				buf := vst2.NewFloatBuffer(numChannels, blockSize)
+				defer buf.Free()
+				b.SetBytes(int64(8 * numChannels * blockSize))
+				for i := 0; i < b.N; i++ {
+					buf.CopyFrom(s)
+					buf.CopyTo(s)
+				}
+			})
+		}
+	}
+}
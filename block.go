@@ -0,0 +1,127 @@
+package vst2
+
+import (
+	"fmt"
+
+	"pipelined.dev/signal"
+)
+
+// blockProcessor contains buffers and methods shared by chain, graph and
+// plugin wrappers. Signals are split into blocks and processed with
+// process function of the owner.
+type blockProcessor struct {
+	numChannels int
+	bufferSize  int
+	in          DoubleBuffer
+	// float buffers are used for plugins that can't process doubles.
+	floatIn  FloatBuffer
+	floatOut FloatBuffer
+	process  func(n int) (DoubleBuffer, error)
+}
+
+// newBlockProcessor allocates input buffer and float buffers of plugin
+// buffer size.
+func newBlockProcessor(numChannels, bufferSize, pluginBufferSize int) blockProcessor {
+	return blockProcessor{
+		numChannels: numChannels,
+		bufferSize:  bufferSize,
+		in:          NewDoubleBuffer(numChannels, bufferSize),
+		floatIn:     NewFloatBuffer(numChannels, pluginBufferSize),
+		floatOut:    NewFloatBuffer(numChannels, pluginBufferSize),
+	}
+}
+
+// Input returns buffer that should be filled with input before Process
+// call.
+func (b *blockProcessor) Input() DoubleBuffer {
+	return b.in
+}
+
+// ProcessFloat64 processes signal in place. Signal is split into blocks
+// of buffer size.
+func (b *blockProcessor) ProcessFloat64(s signal.Float64) error {
+	numChannels := min(s.NumChannels(), b.numChannels)
+	for off := 0; off < s.Size(); off += b.bufferSize {
+		n := min(b.bufferSize, s.Size()-off)
+		in := b.in.slice(n)
+		for i := 0; i < numChannels; i++ {
+			copy(in.Channel(i), s[i][off:off+n])
+		}
+		out, err := b.process(n)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < numChannels; i++ {
+			copy(s[i][off:off+n], out.Channel(i))
+		}
+	}
+	return nil
+}
+
+// processPlugin processes buffer with plugin using float buffers if
+// plugin can't process doubles. Pins are checked on every call, because
+// plugin can change them while resumed. Input is passed through to
+// channels that plugin doesn't output, so they don't keep stale samples.
+func (b *blockProcessor) processPlugin(p *Plugin, in, out DoubleBuffer) error {
+	if err := checkPins(p, b.numChannels); err != nil {
+		return err
+	}
+	if err := processDouble(p, in, out, b.floatIn, b.floatOut); err != nil {
+		return err
+	}
+	for i := p.NumOutputs(); i < out.numChannels; i++ {
+		copy(out.Channel(i), in.Channel(i))
+	}
+	return nil
+}
+
+// checkPins returns error if plugin has more inputs or outputs than
+// buffers have channels. Plugin would access channels past the end of
+// buffer otherwise.
+func checkPins(p *Plugin, numChannels int) error {
+	if in, out := p.NumInputs(), p.NumOutputs(); in > numChannels || out > numChannels {
+		return fmt.Errorf("%s has %d inputs and %d outputs, buffers have %d channels", p.Name, in, out, numChannels)
+	}
+	return nil
+}
+
+// close frees buffers and closes plugins. The first error is returned.
+func (b *blockProcessor) close(plugins ...*Plugin) error {
+	var err error
+	for _, p := range plugins {
+		if e := p.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	b.in.Free()
+	b.floatIn.Free()
+	b.floatOut.Free()
+	b.in = DoubleBuffer{}
+	b.floatIn, b.floatOut = FloatBuffer{}, FloatBuffer{}
+	return err
+}
+
+// processDouble processes buffer with plugin. If plugin can't process
+// doubles, samples are converted with float buffers.
+func processDouble(p *Plugin, in, out DoubleBuffer, fin, fout FloatBuffer) error {
+	if p.CanProcessFloat64() {
+		return p.ProcessDouble(in, out)
+	}
+	fin, fout = fin.slice(in.size), fout.slice(in.size)
+	for i := 0; i < in.numChannels; i++ {
+		src, dst := in.Channel(i), fin.Channel(i)
+		for j := range dst {
+			dst[j] = float32(src[j])
+		}
+	}
+	if err := p.ProcessFloat(fin, fout); err != nil {
+		return err
+	}
+	for i := 0; i < out.numChannels; i++ {
+		src, dst := fout.Channel(i), out.Channel(i)
+		for j := range dst {
+			dst[j] = float64(src[j])
+		}
+	}
+	return nil
+}
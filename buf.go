@@ -3,6 +3,7 @@ package vst2
 // #include <stdlib.h>
 import "C"
 import (
+	"fmt"
 	"unsafe"
 
 	"pipelined.dev/signal"
@@ -11,11 +12,14 @@ import (
 type (
 	// DoubleBuffer is a samples buffer for VST ProcessDouble function.
 	// C requires all buffer channels to be coallocated. This differs from
-	// Go slices.
+	// Go slices. Channels are allocated contiguously in C memory and
+	// exposed as Go slices, so they can be accessed without conversion.
 	DoubleBuffer struct {
 		numChannels int
 		size        int
-		data        []*C.double
+		// mem holds channel pointers followed by samples of all channels.
+		mem      unsafe.Pointer
+		channels [][]float64
 	}
 
 	// FloatBuffer is a samples buffer for VST Process function.
@@ -23,108 +27,205 @@ type (
 	FloatBuffer struct {
 		numChannels int
 		size        int
-		data        []*C.float
+		// mem holds channel pointers followed by samples of all channels.
+		mem      unsafe.Pointer
+		channels [][]float32
 	}
 )
 
 // NewDoubleBuffer allocates new memory for C-compatible buffer.
 func NewDoubleBuffer(numChannels, bufferSize int) DoubleBuffer {
-	b := make([]*C.double, numChannels)
-	for i := 0; i < numChannels; i++ {
-		b[i] = (*C.double)(C.malloc(C.size_t(C.sizeof_double * bufferSize)))
+	if numChannels == 0 {
+		return DoubleBuffer{size: bufferSize}
+	}
+	mem, ptrs := allocChannels(numChannels, bufferSize, C.sizeof_double)
+	channels := make([][]float64, numChannels)
+	for i := range channels {
+		channels[i] = unsafe.Slice((*float64)(ptrs[i]), bufferSize)
 	}
 	return DoubleBuffer{
-		data:        b,
+		mem:         mem,
+		channels:    channels,
 		size:        bufferSize,
 		numChannels: numChannels,
 	}
 }
 
+// Channel returns samples of channel. Slice refers to C memory and is
+// valid until buffer is freed.
+func (b DoubleBuffer) Channel(i int) []float64 {
+	return b.channels[i][:b.size]
+}
+
+// NumChannels returns number of channels in the buffer.
+func (b DoubleBuffer) NumChannels() int {
+	return b.numChannels
+}
+
+// Size returns number of samples per channel.
+func (b DoubleBuffer) Size() int {
+	return b.size
+}
+
 // CopyTo copies values to signal.Float64 buffer. If dimensions differ - the lesser used.
 func (b DoubleBuffer) CopyTo(s signal.Float64) {
-	// determine the size of data by picking up a lesser dimensions.
 	numChannels := min(s.NumChannels(), b.numChannels)
-	bufferSize := min(s.Size(), s.Size())
-
-	// copy data.
 	for i := 0; i < numChannels; i++ {
-		row := (*[1 << 30]C.double)(unsafe.Pointer(b.data[i]))
-		for j := 0; j < bufferSize; j++ {
-			s[i][j] = float64(row[j])
-		}
+		copy(s[i], b.channels[i][:b.size])
 	}
 }
 
 // CopyFrom copies values from signal.Float64. If dimensions differ - the lesser used.
 func (b DoubleBuffer) CopyFrom(s signal.Float64) {
-	// determine the size of data by picking up a lesser dimensions.
 	numChannels := min(s.NumChannels(), b.numChannels)
-	bufferSize := min(s.Size(), s.Size())
-
-	// copy data.
 	for i := 0; i < numChannels; i++ {
-		row := (*[1 << 30]C.double)(unsafe.Pointer(b.data[i]))
-		for j := 0; j < bufferSize; j++ {
-			(*row)[j] = C.double(s[i][j])
-		}
+		copy(b.channels[i][:b.size], s[i])
 	}
 }
 
-// Free the allocated memory.
-func (b DoubleBuffer) Free() {
-	for _, c := range b.data {
-		C.free(unsafe.Pointer(c))
-	}
+// slice returns a buffer that refers to the first n samples.
+func (b DoubleBuffer) slice(n int) DoubleBuffer {
+	b.size = min(n, b.size)
+	return b
+}
+
+// ptr returns C double** that refers to channels.
+func (b DoubleBuffer) ptr() **C.double {
+	return (**C.double)(b.mem)
+}
+
+// Free the allocated memory. Repeated calls are no-op, but copies of
+// buffer must not be freed again.
+func (b *DoubleBuffer) Free() {
+	C.free(b.mem)
+	b.mem = nil
 }
 
 // NewFloatBuffer allocates new memory for C-compatible buffer.
 func NewFloatBuffer(numChannels, bufferSize int) FloatBuffer {
-	b := make([]*C.float, numChannels)
-	for i := 0; i < numChannels; i++ {
-		b[i] = (*C.float)(C.malloc(C.size_t(C.sizeof_float * bufferSize)))
+	if numChannels == 0 {
+		return FloatBuffer{size: bufferSize}
+	}
+	mem, ptrs := allocChannels(numChannels, bufferSize, C.sizeof_float)
+	channels := make([][]float32, numChannels)
+	for i := range channels {
+		channels[i] = unsafe.Slice((*float32)(ptrs[i]), bufferSize)
 	}
 	return FloatBuffer{
-		data:        b,
+		mem:         mem,
+		channels:    channels,
 		size:        bufferSize,
 		numChannels: numChannels,
 	}
 }
 
+// Channel returns samples of channel. Slice refers to C memory and is
+// valid until buffer is freed.
+func (b FloatBuffer) Channel(i int) []float32 {
+	return b.channels[i][:b.size]
+}
+
+// NumChannels returns number of channels in the buffer.
+func (b FloatBuffer) NumChannels() int {
+	return b.numChannels
+}
+
+// Size returns number of samples per channel.
+func (b FloatBuffer) Size() int {
+	return b.size
+}
+
 // CopyTo copies values to signal.Float64 buffer. If dimensions differ - the lesser used.
 func (b FloatBuffer) CopyTo(s signal.Float64) {
-	// determine the size of data by picking up a lesser dimensions.
 	numChannels := min(s.NumChannels(), b.numChannels)
-	bufferSize := min(s.Size(), s.Size())
-
-	// copy data.
+	bufferSize := min(s.Size(), b.size)
 	for i := 0; i < numChannels; i++ {
-		row := (*[1 << 30]C.float)(unsafe.Pointer(b.data[i]))
-		for j := 0; j < bufferSize; j++ {
-			s[i][j] = float64(row[j])
+		row := b.channels[i][:bufferSize]
+		for j, v := range row {
+			s[i][j] = float64(v)
 		}
 	}
 }
 
 // CopyFrom copies values from signal.Float64. If dimensions differ - the lesser used.
 func (b FloatBuffer) CopyFrom(s signal.Float64) {
-	// determine the size of data by picking up a lesser dimensions.
 	numChannels := min(s.NumChannels(), b.numChannels)
-	bufferSize := min(s.Size(), s.Size())
-
-	// copy data.
+	bufferSize := min(s.Size(), b.size)
 	for i := 0; i < numChannels; i++ {
-		row := (*[1 << 30]C.float)(unsafe.Pointer(b.data[i]))
-		for j := 0; j < bufferSize; j++ {
-			(*row)[j] = C.float(s[i][j])
+		row := b.channels[i][:bufferSize]
+		for j := range row {
+			row[j] = float32(s[i][j])
 		}
 	}
 }
 
-// Free the allocated memory.
-func (b FloatBuffer) Free() {
-	for _, c := range b.data {
+// slice returns a buffer that refers to the first n samples.
+func (b FloatBuffer) slice(n int) FloatBuffer {
+	b.size = min(n, b.size)
+	return b
+}
+
+// ptr returns C float** that refers to channels.
+func (b FloatBuffer) ptr() **C.float {
+	return (**C.float)(b.mem)
+}
+
+// Free the allocated memory. Repeated calls are no-op, but copies of
+// buffer must not be freed again.
+func (b *FloatBuffer) Free() {
+	C.free(b.mem)
+	b.mem = nil
+}
+
+// allocChannels makes a single allocation for channel pointers and
+// samples. Returned slice contains pointers to channels. It panics if
+// memory can't be allocated.
+func allocChannels(numChannels, bufferSize int, sampleSize C.size_t) (unsafe.Pointer, []unsafe.Pointer) {
+	ptrSize := C.size_t(unsafe.Sizeof(uintptr(0)))
+	size := C.size_t(numChannels) * (ptrSize + C.size_t(bufferSize)*sampleSize)
+	mem := C.calloc(size, 1)
+	if mem == nil {
+		panic(fmt.Sprintf("failed to allocate %d bytes for %d channels of %d samples", size, numChannels, bufferSize))
+	}
+	ptrs := unsafe.Slice((*unsafe.Pointer)(mem), numChannels)
+	samples := unsafe.Add(mem, int(ptrSize)*numChannels)
+	for i := range ptrs {
+		ptrs[i] = unsafe.Add(samples, i*bufferSize*int(sampleSize))
+	}
+	return mem, ptrs
+}
+
+// newFloatChannels allocates C float** buffer. It's used where buffer
+// is referenced from C structures.
+func newFloatChannels(numChannels, bufferSize int) unsafe.Pointer {
+	if numChannels == 0 {
+		return nil
+	}
+	b := C.malloc(C.size_t(numChannels) * C.size_t(unsafe.Sizeof(uintptr(0))))
+	channels := floatChannels(b, numChannels)
+	for i := range channels {
+		channels[i] = (*C.float)(C.calloc(C.size_t(bufferSize), C.sizeof_float))
+	}
+	return b
+}
+
+// freeFloatChannels frees C float** buffer.
+func freeFloatChannels(b unsafe.Pointer, numChannels int) {
+	if b == nil {
+		return
+	}
+	for _, c := range floatChannels(b, numChannels) {
 		C.free(unsafe.Pointer(c))
 	}
+	C.free(b)
+}
+
+// floatChannels returns slice that refers to C float** buffer.
+func floatChannels(b unsafe.Pointer, numChannels int) []*C.float {
+	if b == nil || numChannels <= 0 {
+		return nil
+	}
+	return unsafe.Slice((**C.float)(b), numChannels)
 }
 
 func min(a, b int) int {
@@ -133,3 +234,10 @@ func min(a, b int) int {
 	}
 	return b
 }
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,36 @@
+package vst2_test
+
+import (
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuffers(t *testing.T) {
+	s := signal.Float64{{1, 2, 3}, {4, 5, 6}}
+
+	d := vst2.NewDoubleBuffer(2, 3)
+	defer d.Free()
+	d.CopyFrom(s)
+	assert.Equal(t, []float64{4, 5, 6}, d.Channel(1))
+	d.Channel(0)[1] = 7
+	out := signal.Float64Buffer(2, 3)
+	d.CopyTo(out)
+	assert.Equal(t, signal.Float64{{1, 7, 3}, {4, 5, 6}}, out)
+
+	f := vst2.NewFloatBuffer(2, 2)
+	defer f.Free()
+	f.CopyFrom(s)
+	assert.Equal(t, []float32{4, 5}, f.Channel(1))
+	out = signal.Float64Buffer(2, 3)
+	f.CopyTo(out)
+	assert.Equal(t, signal.Float64{{1, 2, 0}, {4, 5, 0}}, out)
+
+	// repeated free is no-op.
+	b := vst2.NewDoubleBuffer(2, 3)
+	b.Free()
+	b.Free()
+}
@@ -0,0 +1,18 @@
+package vst2
+
+import "testing"
+
+// BenchmarkHostCallbackLookup measures lookup and call of host callback,
+// it doesn't include cgo overhead of plugin call. See
+// BenchmarkHostCallback for the whole round trip.
+func BenchmarkHostCallbackLookup(b *testing.B) {
+	e := new(effect)
+	i := registerCallback(e, func(HostOpcode, Index, Value, Ptr, Opt) Return {
+		return 1
+	})
+	defer unregisterCallback(i)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hostCallback(e, int64(HostGetSampleRate), 0, 0, nil, 0)
+	}
+}
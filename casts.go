@@ -1,9 +1,12 @@
 package vst2
 
-import "unsafe"
+import (
+	"bytes"
+	"unsafe"
+)
 
 // Value cast used in EffSetSpeakerArrangement call.
-func (sa *SpeakerArrangement) Value() Value {
+func (sa *speakerArrangement) Value() Value {
 	if sa == nil {
 		return 0
 	}
@@ -11,7 +14,7 @@ func (sa *SpeakerArrangement) Value() Value {
 }
 
 // Ptr cast used in EffSetSpeakerArrangement call.
-func (sa *SpeakerArrangement) Ptr() Ptr {
+func (sa *speakerArrangement) Ptr() Ptr {
 	if sa == nil {
 		return nil
 	}
@@ -25,3 +28,22 @@ func (ti *TimeInfo) Return() Return {
 	}
 	return Return(uintptr(unsafe.Pointer(ti)))
 }
+
+// timeInfo casts result of HostGetTime call. Result holds address of
+// C-allocated time info, so it's reinterpreted in place.
+func (r Return) timeInfo() *TimeInfo {
+	return *(**TimeInfo)(unsafe.Pointer(&r))
+}
+
+// Ptr cast used in EffGetInputProperties and EffGetOutputProperties calls.
+func (pp *pinProperties) Ptr() Ptr {
+	return Ptr(unsafe.Pointer(pp))
+}
+
+// goString converts null-terminated C string to Go string.
+func goString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
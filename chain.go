@@ -0,0 +1,215 @@
+package vst2
+
+import (
+	"fmt"
+)
+
+type (
+	// Chain processes audio with plugins in series. Plugins are processed
+	// over shared C buffers: output of one plugin is passed as input of
+	// the next one without copies to Go memory. Chain owns plugins and
+	// closes them on Close. Plugins must be configured and resumed by
+	// the caller, their buffer size must not be less than chain's one.
+	Chain struct {
+		blockProcessor
+		slots []chainSlot
+		// out is swapped with input after every processed plugin.
+		out DoubleBuffer
+	}
+
+	chainSlot struct {
+		plugin *Plugin
+		bypass bool
+	}
+
+	// ChainState is a saved state of chain.
+	ChainState struct {
+		Slots []SlotState `json:"slots"`
+	}
+
+	// SlotState is a saved state of chain slot. Chunk is saved if plugin
+	// supports chunks, parameter values otherwise.
+	SlotState struct {
+		UniqueID int32     `json:"uniqueID"`
+		Name     string    `json:"name"`
+		Bypass   bool      `json:"bypass"`
+		Chunk    []byte    `json:"chunk,omitempty"`
+		Params   []float32 `json:"params,omitempty"`
+	}
+)
+
+// NewChain allocates buffers for provided dimensions and returns chain
+// of plugins. Plugins must not have more pins than numChannels.
+func NewChain(numChannels, bufferSize int, plugins ...*Plugin) (*Chain, error) {
+	for _, p := range plugins {
+		if err := checkPins(p, numChannels); err != nil {
+			return nil, err
+		}
+	}
+	c := Chain{
+		blockProcessor: newBlockProcessor(numChannels, bufferSize, bufferSize),
+		out:            NewDoubleBuffer(numChannels, bufferSize),
+	}
+	c.process = c.Process
+	for _, p := range plugins {
+		c.slots = append(c.slots, chainSlot{plugin: p})
+	}
+	return &c, nil
+}
+
+// Len returns number of slots in the chain.
+func (c *Chain) Len() int {
+	return len(c.slots)
+}
+
+// Plugin returns plugin in the slot.
+func (c *Chain) Plugin(i int) *Plugin {
+	return c.slots[i].plugin
+}
+
+// Insert adds plugin at position i. If i equals to Len, plugin is
+// appended. Plugin must not have more pins than chain has channels.
+func (c *Chain) Insert(i int, p *Plugin) error {
+	if err := checkPins(p, c.numChannels); err != nil {
+		return err
+	}
+	c.slots = append(c.slots, chainSlot{})
+	copy(c.slots[i+1:], c.slots[i:])
+	c.slots[i] = chainSlot{plugin: p}
+	return nil
+}
+
+// Remove removes slot from the chain and returns its plugin. Chain
+// doesn't own removed plugin and caller must close it.
+func (c *Chain) Remove(i int) *Plugin {
+	p := c.slots[i].plugin
+	c.slots = append(c.slots[:i], c.slots[i+1:]...)
+	return p
+}
+
+// Move moves slot from one position to another.
+func (c *Chain) Move(from, to int) {
+	s := c.slots[from]
+	c.slots = append(c.slots[:from], c.slots[from+1:]...)
+	c.slots = append(c.slots, chainSlot{})
+	copy(c.slots[to+1:], c.slots[to:])
+	c.slots[to] = s
+}
+
+// SetBypass sets bypass of the slot. Bypassed plugins are not processed.
+func (c *Chain) SetBypass(i int, bypass bool) {
+	c.slots[i].bypass = bypass
+}
+
+// Bypassed returns true if slot is bypassed.
+func (c *Chain) Bypassed(i int) bool {
+	return c.slots[i].bypass
+}
+
+// InitialDelay returns latency of the chain in samples. It's a sum of
+// latencies of plugins that are not bypassed.
+func (c *Chain) InitialDelay() int {
+	var delay int
+	for _, s := range c.slots {
+		if !s.bypass {
+			delay += s.plugin.InitialDelay()
+		}
+	}
+	return delay
+}
+
+// Process processes first n samples of input buffer with all plugins
+// that are not bypassed. Returned buffer contains output and is valid
+// until the next call.
+func (c *Chain) Process(n int) (DoubleBuffer, error) {
+	if n > c.bufferSize {
+		return DoubleBuffer{}, fmt.Errorf("block size %d exceeds chain buffer size %d", n, c.bufferSize)
+	}
+	in, out := c.in.slice(n), c.out.slice(n)
+	for _, s := range c.slots {
+		if s.bypass {
+			continue
+		}
+		if err := c.processPlugin(s.plugin, in, out); err != nil {
+			return DoubleBuffer{}, fmt.Errorf("failed to process %s: %w", s.plugin.Name, err)
+		}
+		in, out = out, in
+	}
+	return in, nil
+}
+
+// Save returns state of all slots.
+func (c *Chain) Save() ChainState {
+	state := ChainState{Slots: make([]SlotState, 0, len(c.slots))}
+	for _, s := range c.slots {
+		ss := SlotState{
+			UniqueID: s.plugin.UniqueID(),
+			Name:     s.plugin.Name,
+			Bypass:   s.bypass,
+		}
+		if ss.Chunk = s.plugin.Chunk(false); ss.Chunk == nil {
+			ss.Params = make([]float32, s.plugin.NumParams())
+			for i := range ss.Params {
+				ss.Params[i] = s.plugin.Parameter(i)
+			}
+		}
+		state.Slots = append(state.Slots, ss)
+	}
+	return state
+}
+
+// Restore applies saved state to the chain. Chain must contain the same
+// plugins, they're reordered to match the saved order.
+func (c *Chain) Restore(state ChainState) error {
+	if len(state.Slots) != len(c.slots) {
+		return fmt.Errorf("state has %d slots, chain has %d", len(state.Slots), len(c.slots))
+	}
+	slots := make([]chainSlot, 0, len(c.slots))
+	used := make([]bool, len(c.slots))
+	for _, ss := range state.Slots {
+		i := c.find(ss.UniqueID, used)
+		if i == -1 {
+			return fmt.Errorf("plugin %s with id %d not found in chain", ss.Name, ss.UniqueID)
+		}
+		used[i] = true
+		slots = append(slots, chainSlot{plugin: c.slots[i].plugin, bypass: ss.Bypass})
+	}
+	for i, ss := range state.Slots {
+		p := slots[i].plugin
+		if ss.Chunk != nil {
+			if err := p.SetChunk(ss.Chunk, false); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", p.Name, err)
+			}
+			continue
+		}
+		for j, v := range ss.Params {
+			if err := p.SetParameter(j, v); err != nil {
+				return fmt.Errorf("failed to restore %s: %w", p.Name, err)
+			}
+		}
+	}
+	c.slots = slots
+	return nil
+}
+
+// find returns index of the first unused slot with plugin id.
+func (c *Chain) find(id int32, used []bool) int {
+	for i, s := range c.slots {
+		if !used[i] && s.plugin.UniqueID() == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Close closes all plugins and frees buffers.
+func (c *Chain) Close() error {
+	plugins := make([]*Plugin, 0, len(c.slots))
+	for _, s := range c.slots {
+		plugins = append(plugins, s.plugin)
+	}
+	c.slots = nil
+	c.out.Free()
+	c.out = DoubleBuffer{}
+	return c.close(plugins...)
+}
@@ -0,0 +1,82 @@
+package vst2_test
+
+import (
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadResumed(t *testing.T, v *vst2.VST, bufferSize int) *vst2.Plugin {
+	t.Helper()
+	p := v.Load(testHostCallback())
+	require.NotNil(t, p)
+	require.Nil(t, p.SetSampleRate(sampleRate))
+	require.Nil(t, p.SetBufferSize(bufferSize))
+	require.Nil(t, p.Start())
+	return p
+}
+
+func TestChain(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	const bufferSize = 64
+	first, second := loadResumed(t, v, bufferSize), loadResumed(t, v, bufferSize)
+	_, err = vst2.NewChain(1, bufferSize, first)
+	assert.NotNil(t, err, "plugin has more pins than chain has channels")
+	c, err := vst2.NewChain(2, bufferSize, first, second)
+	require.Nil(t, err)
+	defer c.Close()
+	assert.Equal(t, first.InitialDelay()+second.InitialDelay(), c.InitialDelay())
+
+	first.SetParameter(0, 0.5)
+	second.SetParameter(0, 0.25)
+	state := c.Save()
+
+	c.Move(1, 0)
+	assert.True(t, second == c.Plugin(0))
+	c.SetBypass(0, true)
+	s := signal.Float64{make([]float64, 100), make([]float64, 100)}
+	s[0][99] = 1
+	require.Nil(t, c.ProcessFloat64(s))
+	assert.Equal(t, first.Parameter(0), float32(s[0][99]))
+
+	first.SetParameter(0, 1)
+	require.Nil(t, c.Restore(state))
+	assert.False(t, c.Bypassed(0))
+	assert.Equal(t, float32(0.5), c.Plugin(0).Parameter(0))
+	assert.Equal(t, float32(0.25), c.Plugin(1).Parameter(0))
+
+	removed := c.Remove(1)
+	assert.Equal(t, 1, c.Len())
+	require.Nil(t, c.Insert(0, removed))
+	assert.True(t, removed == c.Plugin(0))
+}
+
+func TestChainPins(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	const bufferSize = 64
+	p := loadResumed(t, v, bufferSize)
+	numChannels := p.NumOutputs() + 1
+	c, err := vst2.NewChain(numChannels, bufferSize, p)
+	require.Nil(t, err)
+	defer c.Close()
+
+	// channel without plugin output passes input through.
+	s := signal.Float64Buffer(numChannels, 100)
+	for i := range s[numChannels-1] {
+		s[numChannels-1][i] = 1
+	}
+	require.Nil(t, c.ProcessFloat64(s))
+	for _, v := range s[numChannels-1] {
+		require.Equal(t, 1.0, v)
+	}
+}
@@ -0,0 +1,49 @@
+package vst2
+
+import (
+	"time"
+
+	"pipelined.dev/signal"
+)
+
+// Clock provides time that host reports to plugins. Position is a number
+// of samples processed since start.
+type Clock interface {
+	Now(position int64, sampleRate signal.SampleRate) time.Time
+}
+
+type (
+	// WallClock reports system time. Renders that use it aren't
+	// reproducible.
+	WallClock struct{}
+
+	// SampleClock derives time from number of processed samples, so
+	// offline renders report the same time as real-time playback.
+	SampleClock struct {
+		// Start is a time of the first sample.
+		Start time.Time
+	}
+
+	// FixedClock always reports the same time.
+	FixedClock struct {
+		Time time.Time
+	}
+)
+
+// Now returns system time.
+func (WallClock) Now(int64, signal.SampleRate) time.Time {
+	return time.Now()
+}
+
+// Now returns start time plus duration of processed samples.
+func (c SampleClock) Now(position int64, sampleRate signal.SampleRate) time.Time {
+	if sampleRate == 0 {
+		return c.Start
+	}
+	return c.Start.Add(sampleRate.DurationOf(int(position)))
+}
+
+// Now returns fixed time.
+func (c FixedClock) Now(int64, signal.SampleRate) time.Time {
+	return c.Time
+}
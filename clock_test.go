@@ -0,0 +1,28 @@
+package vst2_test
+
+import (
+	"testing"
+	"time"
+
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		clock    vst2.Clock
+		position int64
+		expected time.Time
+	}{
+		{vst2.SampleClock{Start: start}, 0, start},
+		{vst2.SampleClock{Start: start}, 44100, start.Add(time.Second)},
+		{vst2.SampleClock{Start: start}, 441, start.Add(10 * time.Millisecond)},
+		{vst2.FixedClock{Time: start}, 44100, start},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, test.clock.Now(test.position, 44100))
+	}
+	assert.False(t, vst2.WallClock{}.Now(0, 44100).IsZero())
+}
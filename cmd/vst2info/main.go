@@ -0,0 +1,243 @@
+// Command vst2info prints metadata of VST2 plugin.
+//
+// Usage:
+//
+//	vst2info [-json] [-samplerate 44100] [-block 512] plugin.vst
+//
+// Plugin is resumed with provided sample rate and block size to report
+// latency and tail size, because plugins often compute them on resume.
+//
+// Exit code is 1 if plugin can't be loaded and 2 if arguments are
+// invalid.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"pipelined.dev/vst2"
+)
+
+// Exit codes.
+const (
+	exitPlugin = 1
+	exitUsage  = 2
+)
+
+type (
+	info struct {
+		Path          string `json:"path"`
+		Name          string `json:"name"`
+		Vendor        string `json:"vendor"`
+		Product       string `json:"product"`
+		UniqueID      int32  `json:"uniqueID"`
+		UniqueIDCode  string `json:"uniqueIDCode"`
+		Version       int32  `json:"version"`
+		VendorVersion int    `json:"vendorVersion"`
+		VSTVersion    int    `json:"vstVersion"`
+		Category      string `json:"category"`
+		Inputs        int    `json:"inputs"`
+		Outputs       int    `json:"outputs"`
+		// Flags are decoded names of set flags.
+		Flags          []string    `json:"flags"`
+		Latency        int         `json:"latency"`
+		TailSize       int         `json:"tailSize"`
+		Params         []paramInfo `json:"params"`
+		CurrentProgram int         `json:"currentProgram"`
+		Programs       []string    `json:"programs"`
+		CanDo          []canDoInfo `json:"canDo"`
+	}
+
+	paramInfo struct {
+		Index   int     `json:"index"`
+		Name    string  `json:"name"`
+		Label   string  `json:"label"`
+		Display string  `json:"display"`
+		Value   float32 `json:"value"`
+	}
+
+	canDoInfo struct {
+		Capability vst2.PluginCanDo `json:"capability"`
+		Response   string           `json:"response"`
+	}
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes command and returns exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	var (
+		flags      = flag.NewFlagSet("vst2info", flag.ContinueOnError)
+		asJSON     = flags.Bool("json", false, "print JSON instead of text")
+		sampleRate = flags.Int("samplerate", 44100, "sample `rate` used to resume plugin")
+		block      = flags.Int("block", 512, "block `size` used to resume plugin")
+	)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vst2info [flags] plugin")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 1 || *sampleRate <= 0 || *block <= 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	i, err := inspect(flags.Arg(0), *sampleRate, *block)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitPlugin
+	}
+	if *asJSON {
+		e := json.NewEncoder(stdout)
+		e.SetIndent("", "  ")
+		err = e.Encode(i)
+	} else {
+		err = i.writeText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitPlugin
+	}
+	return 0
+}
+
+// inspect loads plugin and collects its metadata.
+func inspect(path string, sampleRate, block int) (info, error) {
+	v, err := vst2.Open(path)
+	if err != nil {
+		return info{}, err
+	}
+	defer v.Close()
+	p := v.Load(func(opcode vst2.HostOpcode, index vst2.Index, value vst2.Value, ptr vst2.Ptr, opt vst2.Opt) vst2.Return {
+		switch opcode {
+		case vst2.HostGetSampleRate:
+			return vst2.Return(sampleRate)
+		case vst2.HostGetBlockSize:
+			return vst2.Return(block)
+		}
+		return 0
+	})
+	if p == nil {
+		return info{}, fmt.Errorf("failed to load plugin %s", path)
+	}
+	defer p.Close()
+
+	i := info{
+		Path:           v.Path,
+		Name:           p.EffectName(),
+		Vendor:         p.Vendor(),
+		Product:        p.Product(),
+		UniqueID:       p.UniqueID(),
+		UniqueIDCode:   fourCC(p.UniqueID()),
+		Version:        p.Version(),
+		VendorVersion:  p.VendorVersion(),
+		VSTVersion:     p.VSTVersion(),
+		Category:       p.Category().String(),
+		Inputs:         p.NumInputs(),
+		Outputs:        p.NumOutputs(),
+		Flags:          p.Flags().Names(),
+		CurrentProgram: p.Program(),
+		Params:         make([]paramInfo, p.NumParams()),
+		Programs:       make([]string, p.NumPrograms()),
+	}
+	for j := range i.Params {
+		i.Params[j] = paramInfo{
+			Index:   j,
+			Name:    p.ParamName(j),
+			Label:   p.ParamLabel(j),
+			Display: p.ParamDisplay(j),
+			Value:   p.Parameter(j),
+		}
+	}
+	for j := range i.Programs {
+		i.Programs[j] = p.ProgramNameIndexed(j)
+	}
+	for _, c := range vst2.PluginCanDos() {
+		i.CanDo = append(i.CanDo, canDoInfo{Capability: c, Response: p.CanDo(c).String()})
+	}
+
+	if err := p.SetSampleRate(sampleRate); err != nil {
+		return info{}, fmt.Errorf("failed to set sample rate: %w", err)
+	}
+	if err := p.SetBufferSize(block); err != nil {
+		return info{}, fmt.Errorf("failed to set block size: %w", err)
+	}
+	if err := p.Start(); err != nil {
+		return info{}, fmt.Errorf("failed to resume plugin: %w", err)
+	}
+	i.Latency = p.InitialDelay()
+	i.TailSize = p.TailSize()
+	return i, nil
+}
+
+// fourCC returns unique id as four-character code. Empty string is
+// returned if id contains non-printable characters.
+func fourCC(id int32) string {
+	b := []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	for _, c := range b {
+		if c < ' ' || c > '~' {
+			return ""
+		}
+	}
+	return string(b)
+}
+
+func (i info) writeText(w io.Writer) error {
+	id := fmt.Sprintf("%#08x", uint32(i.UniqueID))
+	if i.UniqueIDCode != "" {
+		id = fmt.Sprintf("%s (%s)", i.UniqueIDCode, id)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Path:\t%s\n", i.Path)
+	fmt.Fprintf(tw, "Name:\t%s\n", i.Name)
+	fmt.Fprintf(tw, "Vendor:\t%s\n", i.Vendor)
+	fmt.Fprintf(tw, "Product:\t%s\n", i.Product)
+	fmt.Fprintf(tw, "Unique ID:\t%s\n", id)
+	fmt.Fprintf(tw, "Version:\t%d\n", i.Version)
+	fmt.Fprintf(tw, "Vendor version:\t%d\n", i.VendorVersion)
+	fmt.Fprintf(tw, "VST version:\t%d\n", i.VSTVersion)
+	fmt.Fprintf(tw, "Category:\t%s\n", i.Category)
+	fmt.Fprintf(tw, "Inputs:\t%d\n", i.Inputs)
+	fmt.Fprintf(tw, "Outputs:\t%d\n", i.Outputs)
+	fmt.Fprintf(tw, "Flags:\t%s\n", strings.Join(i.Flags, ", "))
+	fmt.Fprintf(tw, "Latency:\t%d samples\n", i.Latency)
+	fmt.Fprintf(tw, "Tail size:\t%d samples\n", i.TailSize)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// every section is aligned separately.
+	fmt.Fprintf(tw, "\nParameters (%d):\n", len(i.Params))
+	for _, p := range i.Params {
+		fmt.Fprintf(tw, "  %d\t%s\t%s %s\t%.4f\n", p.Index, p.Name, p.Display, p.Label, p.Value)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(tw, "\nPrograms (%d):\n", len(i.Programs))
+	for j, name := range i.Programs {
+		current := ""
+		if j == i.CurrentProgram {
+			current = " *"
+		}
+		fmt.Fprintf(tw, "  %d\t%s%s\n", j, name, current)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(tw, "\nCan do:\n")
+	for _, c := range i.CanDo {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.Capability, c.Response)
+	}
+	return tw.Flush()
+}
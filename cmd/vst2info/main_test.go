@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFourCC(t *testing.T) {
+	assert.Equal(t, "Test", fourCC(0x54657374))
+	assert.Equal(t, "", fourCC(1))
+}
+
+func TestWriteText(t *testing.T) {
+	i := info{
+		Name:         "Plugin",
+		UniqueID:     0x54657374,
+		UniqueIDCode: "Test",
+		Flags:        []string{"CanReplacing", "IsSynth"},
+		Params:       []paramInfo{{Index: 0, Name: "Gain", Label: "dB", Display: "-6.0", Value: 0.5}},
+		Programs:     []string{"Init"},
+	}
+	var b bytes.Buffer
+	assert.Nil(t, i.writeText(&b))
+	assert.Contains(t, b.String(), "Test (0x54657374)")
+	assert.Contains(t, b.String(), "CanReplacing, IsSynth")
+	assert.Contains(t, b.String(), "Gain  -6.0 dB  0.5000")
+	assert.Contains(t, b.String(), "Init *")
+}
+
+func TestRun(t *testing.T) {
+	assert.Equal(t, exitUsage, run(nil, io.Discard, io.Discard))
+	assert.Equal(t, exitPlugin, run([]string{"not-exists.vst"}, io.Discard, io.Discard))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"pipelined.dev/signal"
+)
+
+// readAIFF decodes AIFF or uncompressed AIFF-C file.
+func readAIFF(data []byte) (signal.Float64, signal.SampleRate, error) {
+	if len(data) < 12 || string(data[:4]) != "FORM" {
+		return nil, 0, errors.New("not an AIFF file")
+	}
+	aifc := string(data[8:12]) == "AIFC"
+	if !aifc && string(data[8:12]) != "AIFF" {
+		return nil, 0, fmt.Errorf("unsupported form type: %q", data[8:12])
+	}
+	var (
+		format     = sampleFormat{bigEndian: true, signed8: true}
+		sampleRate float64
+		hasFormat  bool
+	)
+	for chunks := data[12:]; len(chunks) >= 8; {
+		id, size := string(chunks[:4]), int(binary.BigEndian.Uint32(chunks[4:8]))
+		chunks = chunks[8:]
+		if size > len(chunks) {
+			size = len(chunks)
+		}
+		body := chunks[:size]
+		switch id {
+		case "COMM":
+			if size < 18 {
+				return nil, 0, fmt.Errorf("invalid COMM chunk size: %d", size)
+			}
+			format.numChannels = int(binary.BigEndian.Uint16(body[0:2]))
+			format.bitDepth = int(binary.BigEndian.Uint16(body[6:8]))
+			sampleRate = extended(body[8:18])
+			if aifc && size >= 22 {
+				if err := format.compression(string(body[18:22])); err != nil {
+					return nil, 0, err
+				}
+			}
+			hasFormat = true
+		case "SSND":
+			if !hasFormat {
+				return nil, 0, errors.New("SSND chunk before COMM chunk")
+			}
+			if size < 8 {
+				return nil, 0, fmt.Errorf("invalid SSND chunk size: %d", size)
+			}
+			offset := int(binary.BigEndian.Uint32(body[0:4]))
+			if 8+offset > size {
+				return nil, 0, fmt.Errorf("invalid SSND offset: %d", offset)
+			}
+			s, err := decode(body[8+offset:], format)
+			return s, signal.SampleRate(sampleRate), err
+		}
+		chunks = chunks[min(size+size%2, len(chunks)):]
+	}
+	return nil, 0, errors.New("SSND chunk not found")
+}
+
+// compression applies AIFF-C compression type to format.
+func (f *sampleFormat) compression(c string) error {
+	switch c {
+	case "NONE":
+	case "sowt":
+		f.bigEndian = false
+	case "fl32", "FL32":
+		f.float, f.bitDepth = true, 32
+	case "fl64", "FL64":
+		f.float, f.bitDepth = true, 64
+	default:
+		return fmt.Errorf("unsupported AIFF-C compression: %q", c)
+	}
+	return nil
+}
+
+// extended converts 80-bit IEEE 754 extended precision number.
+func extended(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]))
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+	sign := 1.0
+	if exponent&0x8000 != 0 {
+		sign = -1
+		exponent &= 0x7FFF
+	}
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+	return sign * math.Ldexp(float64(mantissa), exponent-16383-63)
+}
@@ -0,0 +1,383 @@
+// Command vst2render processes audio file with VST2 plugins offline.
+//
+// Usage:
+//
+//	vst2render -i input.wav -o output.wav [-bits 24] [-float] [-tail 2] \
+//		-plugin first.vst [-preset first.fxp] [-param 0=0.5] \
+//		-plugin second.vst [-chunk second.bin]
+//
+// Input can be WAV or AIFF file. Plugins are processed in series in order
+// of -plugin flags. -preset, -chunk and -param flags are applied to the
+// last plugin declared before them. Output is latency-compensated and
+// extended by tail of plugins.
+//
+// Exit code is 1 if files can't be read or written, 2 if arguments are
+// invalid and 3 if plugin fails.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+)
+
+// Exit codes.
+const (
+	exitIO     = 1
+	exitUsage  = 2
+	exitPlugin = 3
+)
+
+// maxAutoTail limits tail reported by plugins in seconds.
+const maxAutoTail = 30
+
+type (
+	// pluginSpec is a plugin with settings provided in arguments.
+	pluginSpec struct {
+		path    string
+		presets []string
+		chunks  []string
+		params  []param
+	}
+
+	param struct {
+		index int
+		value float32
+	}
+
+	// specFlag appends settings to the last declared plugin.
+	specFlag struct {
+		specs *[]pluginSpec
+		set   func(*pluginSpec, string) error
+	}
+
+	// exitError is an error with exit code.
+	exitError struct {
+		code int
+		err  error
+	}
+)
+
+func (f specFlag) String() string {
+	return ""
+}
+
+func (f specFlag) Set(v string) error {
+	if f.set == nil {
+		*f.specs = append(*f.specs, pluginSpec{path: v})
+		return nil
+	}
+	if len(*f.specs) == 0 {
+		return errors.New("must follow -plugin")
+	}
+	return f.set(&(*f.specs)[len(*f.specs)-1], v)
+}
+
+func (e exitError) Error() string {
+	return e.err.Error()
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run executes command and returns exit code.
+func run(args []string, stderr io.Writer) int {
+	var (
+		flags    = flag.NewFlagSet("vst2render", flag.ContinueOnError)
+		input    = flags.String("i", "", "input WAV or AIFF `file`")
+		output   = flags.String("o", "", "output WAV `file`")
+		bitDepth = flags.Int("bits", 24, "output bit depth: 16, 24 or 32; 32 or 64 with -float")
+		float    = flags.Bool("float", false, "write IEEE float samples")
+		tail     = flags.Float64("tail", -1, "tail length in `seconds`, negative value uses tail reported by plugins")
+		block    = flags.Int("block", 512, "processing block `size`")
+		specs    []pluginSpec
+	)
+	flags.SetOutput(stderr)
+	flags.Var(specFlag{specs: &specs}, "plugin", "plugin `path`, can be repeated")
+	flags.Var(specFlag{specs: &specs, set: func(s *pluginSpec, v string) error {
+		s.presets = append(s.presets, v)
+		return nil
+	}}, "preset", ".fxp or .fxb `file` applied to the last plugin")
+	flags.Var(specFlag{specs: &specs, set: func(s *pluginSpec, v string) error {
+		s.chunks = append(s.chunks, v)
+		return nil
+	}}, "chunk", "program chunk `file` applied to the last plugin")
+	flags.Var(specFlag{specs: &specs, set: func(s *pluginSpec, v string) error {
+		p, err := parseParam(v)
+		if err != nil {
+			return err
+		}
+		s.params = append(s.params, p)
+		return nil
+	}}, "param", "parameter `index=value` applied to the last plugin")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *input == "" || *output == "" || len(specs) == 0 || *block <= 0 || flags.NArg() > 0 {
+		fmt.Fprintln(stderr, "input, output and at least one plugin must be provided")
+		flags.Usage()
+		return exitUsage
+	}
+	if *bitDepth == 8 || !validDepth(*bitDepth, *float) {
+		fmt.Fprintf(stderr, "unsupported bit depth: %d\n", *bitDepth)
+		return exitUsage
+	}
+
+	if err := render(*input, *output, *bitDepth, *float, *tail, *block, specs); err != nil {
+		fmt.Fprintln(stderr, err)
+		var e exitError
+		if errors.As(err, &e) {
+			return e.code
+		}
+		return exitIO
+	}
+	return 0
+}
+
+func parseParam(v string) (param, error) {
+	i := strings.IndexByte(v, '=')
+	if i == -1 {
+		return param{}, fmt.Errorf("parameter must be index=value: %q", v)
+	}
+	index, err := strconv.Atoi(v[:i])
+	if err != nil || index < 0 {
+		return param{}, fmt.Errorf("invalid parameter index: %q", v[:i])
+	}
+	value, err := strconv.ParseFloat(v[i+1:], 32)
+	if err != nil {
+		return param{}, fmt.Errorf("invalid parameter value: %w", err)
+	}
+	return param{index: index, value: float32(value)}, nil
+}
+
+// readAudio reads WAV or AIFF file.
+func readAudio(path string) (signal.Float64, signal.SampleRate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if bytes.HasPrefix(data, []byte("FORM")) {
+		return readAIFF(data)
+	}
+	return readWAV(data)
+}
+
+func render(input, output string, bitDepth int, float bool, tail float64, block int, specs []pluginSpec) error {
+	in, sampleRate, err := readAudio(input)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", input, err)
+	}
+
+	h := host{
+		sampleRate: sampleRate,
+		blockSize:  block,
+		// time is derived from samples, so renders are reproducible.
+		clock: vst2.SampleClock{Start: time.Unix(0, 0)},
+	}
+	plugins := make([]*vst2.Plugin, 0, len(specs))
+	for _, spec := range specs {
+		p, err := h.load(spec.path)
+		if err != nil {
+			for _, p := range plugins {
+				p.Close()
+			}
+			return exitError{code: exitPlugin, err: err}
+		}
+		plugins = append(plugins, p)
+	}
+	numChannels := in.NumChannels()
+	for _, p := range plugins {
+		numChannels = max(numChannels, max(len(p.InputPins()), len(p.OutputPins())))
+	}
+	chain, err := vst2.NewChain(numChannels, block, plugins...)
+	if err != nil {
+		for _, p := range plugins {
+			p.Close()
+		}
+		return exitError{code: exitPlugin, err: err}
+	}
+	defer chain.Close()
+
+	for i, spec := range specs {
+		if err := configure(plugins[i], spec, sampleRate, block); err != nil {
+			return err
+		}
+	}
+
+	latency := chain.InitialDelay()
+	tailSize := int(tail * float64(sampleRate))
+	if tail < 0 {
+		tailSize = 0
+		for _, p := range plugins {
+			// one means that plugin has no tail.
+			if size := p.TailSize(); size > 1 {
+				tailSize += size
+			}
+		}
+		tailSize = min(tailSize, maxAutoTail*int(sampleRate))
+	}
+	total := in.Size() + latency + tailSize
+	for _, p := range plugins {
+		p.SetTotalSamplesToProcess(total)
+		if err := p.Start(); err != nil {
+			return exitError{code: exitPlugin, err: fmt.Errorf("failed to start %s: %w", p.Name, err)}
+		}
+		if err := p.StartProcess(); err != nil {
+			return exitError{code: exitPlugin, err: fmt.Errorf("failed to start processing %s: %w", p.Name, err)}
+		}
+	}
+
+	out := signal.Float64(make([][]float64, in.NumChannels()))
+	for i := range out {
+		out[i] = make([]float64, in.Size()+tailSize)
+	}
+	for h.position = 0; h.position < total; h.position += block {
+		n := min(block, total-h.position)
+		buf := chain.Input()
+		for i := 0; i < numChannels; i++ {
+			ch := buf.Channel(i)[:n]
+			for j := range ch {
+				ch[j] = 0
+			}
+			if i < in.NumChannels() && h.position < in.Size() {
+				copy(ch, in[i][h.position:])
+			}
+		}
+		processed, err := chain.Process(n)
+		if err != nil {
+			return exitError{code: exitPlugin, err: err}
+		}
+		// skip latency at the beginning of output.
+		skip := max(0, latency-h.position)
+		for i := range out {
+			if skip < n {
+				copy(out[i][h.position+skip-latency:], processed.Channel(i)[skip:])
+			}
+		}
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	if err := writeWAV(f, out, sampleRate, bitDepth, float); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	return f.Close()
+}
+
+// configure sets up suspended plugin and applies settings.
+func configure(p *vst2.Plugin, spec pluginSpec, sampleRate signal.SampleRate, block int) error {
+	if err := p.SetSampleRate(int(sampleRate)); err != nil {
+		return exitError{code: exitPlugin, err: fmt.Errorf("failed to set sample rate of %s: %w", p.Name, err)}
+	}
+	if err := p.SetBufferSize(block); err != nil {
+		return exitError{code: exitPlugin, err: fmt.Errorf("failed to set buffer size of %s: %w", p.Name, err)}
+	}
+	for _, path := range spec.presets {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open preset: %w", err)
+		}
+		preset, err := vst2.ReadPreset(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read preset %s: %w", path, err)
+		}
+		if err := p.LoadPreset(preset); err != nil {
+			return exitError{code: exitPlugin, err: fmt.Errorf("failed to load preset %s: %w", path, err)}
+		}
+	}
+	for _, path := range spec.chunks {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read chunk: %w", err)
+		}
+		if err := p.SetChunk(data, true); err != nil {
+			return exitError{code: exitPlugin, err: fmt.Errorf("failed to set chunk %s: %w", path, err)}
+		}
+	}
+	for _, param := range spec.params {
+		if param.index >= p.NumParams() {
+			return exitError{code: exitUsage, err: fmt.Errorf("%s has %d params, got index %d", p.Name, p.NumParams(), param.index)}
+		}
+		if err := p.SetParameter(param.index, param.value); err != nil {
+			return exitError{code: exitPlugin, err: fmt.Errorf("failed to set param %d of %s: %w", param.index, p.Name, err)}
+		}
+	}
+	return nil
+}
+
+// host provides offline processing context to plugins.
+type host struct {
+	sampleRate signal.SampleRate
+	blockSize  int
+	position   int
+	clock      vst2.Clock
+}
+
+// load opens library and loads plugin. Plugin holds reference to
+// the library, so it's released right away.
+func (h *host) load(path string) (*vst2.Plugin, error) {
+	v, err := vst2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer v.Close()
+	var p *vst2.Plugin
+	p = v.Load(func(opcode vst2.HostOpcode, index vst2.Index, value vst2.Value, ptr vst2.Ptr, opt vst2.Opt) vst2.Return {
+		switch opcode {
+		case vst2.HostVersion:
+			return 2400
+		case vst2.HostGetCurrentProcessLevel:
+			return vst2.Return(vst2.ProcessLevelOffline)
+		case vst2.HostGetSampleRate:
+			return vst2.Return(h.sampleRate)
+		case vst2.HostGetBlockSize:
+			return vst2.Return(h.blockSize)
+		case vst2.HostGetTime:
+			// plugin can call back during open.
+			if p == nil {
+				return 0
+			}
+			ti := p.TimeInfo()
+			*ti = vst2.TimeInfo{
+				SamplePos:   float64(h.position),
+				SampleRate:  float64(h.sampleRate),
+				NanoSeconds: float64(h.clock.Now(int64(h.position), h.sampleRate).UnixNano()),
+				Flags:       vst2.NanosValid,
+			}
+			return ti.Return()
+		}
+		return 0
+	})
+	if p == nil {
+		return nil, fmt.Errorf("failed to load plugin %s", path)
+	}
+	return p, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
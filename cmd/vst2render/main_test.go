@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"pipelined.dev/signal"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAV(t *testing.T) {
+	s := signal.Float64{
+		{0, 0.5, -0.5, 1, -1},
+		{0.25, -0.25, 2, -2, 0.125},
+	}
+	tests := []struct {
+		bitDepth int
+		float    bool
+		delta    float64
+	}{
+		{16, false, 1.0 / (1 << 15)},
+		{24, false, 1.0 / (1 << 23)},
+		{32, false, 1.0 / (1 << 31)},
+		{32, true, 0},
+		{64, true, 0},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		require.Nil(t, writeWAV(&b, s, 48000, test.bitDepth, test.float))
+		result, sampleRate, err := readWAV(b.Bytes())
+		require.Nil(t, err)
+		assert.Equal(t, signal.SampleRate(48000), sampleRate)
+		require.Equal(t, s.NumChannels(), result.NumChannels())
+		require.Equal(t, s.Size(), result.Size())
+		for i := range s {
+			for j, v := range s[i] {
+				if !test.float {
+					// integer samples are clipped.
+					v = clip(v)
+				}
+				assert.InDelta(t, v, result[i][j], test.delta+1e-9, "bits %d float %v", test.bitDepth, test.float)
+			}
+		}
+	}
+
+	// odd data size is padded and included in RIFF size.
+	var b bytes.Buffer
+	require.Nil(t, writeWAV(&b, signal.Float64{{0, 0.5, 1}}, 48000, 24, false))
+	assert.Equal(t, 44+9+1, b.Len())
+	assert.Equal(t, uint32(b.Len()-8), binary.LittleEndian.Uint32(b.Bytes()[4:8]))
+	assert.Equal(t, uint32(9), binary.LittleEndian.Uint32(b.Bytes()[40:44]))
+}
+
+func clip(v float64) float64 {
+	if v > 1 {
+		return 1
+	}
+	if v < -1 {
+		return -1
+	}
+	return v
+}
+
+func TestAIFF(t *testing.T) {
+	var b bytes.Buffer
+	b.WriteString("FORM")
+	binary.Write(&b, binary.BigEndian, uint32(0))
+	b.WriteString("AIFFCOMM")
+	binary.Write(&b, binary.BigEndian, uint32(18))
+	binary.Write(&b, binary.BigEndian, []uint16{1, 0, 2, 16})
+	// 44100 as 80-bit extended.
+	b.Write([]byte{0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0})
+	b.WriteString("SSND")
+	binary.Write(&b, binary.BigEndian, []uint32{12, 0, 0})
+	binary.Write(&b, binary.BigEndian, []int16{1 << 14, -1 << 14})
+
+	s, sampleRate, err := readAIFF(b.Bytes())
+	require.Nil(t, err)
+	assert.Equal(t, signal.SampleRate(44100), sampleRate)
+	assert.Equal(t, signal.Float64{{0.5, -0.5}}, s)
+}
+
+func TestRun(t *testing.T) {
+	assert.Equal(t, exitUsage, run([]string{"-i", "in.wav", "-o", "out.wav"}, io.Discard))
+	assert.Equal(t, exitUsage, run([]string{"-param", "0=1", "-plugin", "plugin.vst"}, io.Discard))
+	assert.Equal(t, exitUsage, run([]string{"-i", "in.wav", "-o", "out.wav", "-plugin", "plugin.vst", "-bits", "12"}, io.Discard))
+
+	_, err := parseParam("1=0.5")
+	assert.Nil(t, err)
+	_, err = parseParam("0.5")
+	assert.NotNil(t, err)
+}
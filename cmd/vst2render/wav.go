@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"pipelined.dev/signal"
+)
+
+// WAV format tags.
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// sampleFormat describes encoding of interleaved samples.
+type sampleFormat struct {
+	numChannels int
+	bitDepth    int
+	float       bool
+	bigEndian   bool
+	// signed8 is true if 8-bit samples are signed.
+	signed8 bool
+}
+
+// readWAV decodes RIFF WAVE file.
+func readWAV(data []byte) (signal.Float64, signal.SampleRate, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, 0, errors.New("not a WAV file")
+	}
+	var (
+		format     sampleFormat
+		sampleRate uint32
+		hasFormat  bool
+	)
+	for chunks := data[12:]; len(chunks) >= 8; {
+		id, size := string(chunks[:4]), int(binary.LittleEndian.Uint32(chunks[4:8]))
+		chunks = chunks[8:]
+		if size > len(chunks) {
+			size = len(chunks)
+		}
+		body := chunks[:size]
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, fmt.Errorf("invalid fmt chunk size: %d", size)
+			}
+			tag := binary.LittleEndian.Uint16(body[0:2])
+			if tag == wavFormatExtensible && size >= 26 {
+				// sub format GUID starts with format tag.
+				tag = binary.LittleEndian.Uint16(body[24:26])
+			}
+			format = sampleFormat{
+				numChannels: int(binary.LittleEndian.Uint16(body[2:4])),
+				bitDepth:    int(binary.LittleEndian.Uint16(body[14:16])),
+			}
+			switch tag {
+			case wavFormatPCM:
+			case wavFormatFloat:
+				format.float = true
+			default:
+				return nil, 0, fmt.Errorf("unsupported WAV format: %#x", tag)
+			}
+			sampleRate = binary.LittleEndian.Uint32(body[4:8])
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return nil, 0, errors.New("data chunk before fmt chunk")
+			}
+			s, err := decode(body, format)
+			return s, signal.SampleRate(sampleRate), err
+		}
+		// chunks are word-aligned.
+		chunks = chunks[min(size+size%2, len(chunks)):]
+	}
+	return nil, 0, errors.New("data chunk not found")
+}
+
+// decode converts interleaved samples into signal.
+func decode(data []byte, f sampleFormat) (signal.Float64, error) {
+	if f.numChannels <= 0 {
+		return nil, fmt.Errorf("invalid number of channels: %d", f.numChannels)
+	}
+	if !validDepth(f.bitDepth, f.float) {
+		return nil, fmt.Errorf("unsupported bit depth: %d", f.bitDepth)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if f.bigEndian {
+		order = binary.BigEndian
+	}
+	sampleSize := f.bitDepth / 8
+	size := len(data) / (sampleSize * f.numChannels)
+	s := signal.Float64(make([][]float64, f.numChannels))
+	for i := range s {
+		s[i] = make([]float64, size)
+	}
+	for j := 0; j < size; j++ {
+		for i := range s {
+			b := data[(j*f.numChannels+i)*sampleSize:]
+			s[i][j] = decodeSample(b[:sampleSize], order, f)
+		}
+	}
+	return s, nil
+}
+
+func validDepth(bitDepth int, float bool) bool {
+	if float {
+		return bitDepth == 32 || bitDepth == 64
+	}
+	return bitDepth == 8 || bitDepth == 16 || bitDepth == 24 || bitDepth == 32
+}
+
+func decodeSample(b []byte, order binary.ByteOrder, f sampleFormat) float64 {
+	switch {
+	case f.float && f.bitDepth == 32:
+		return float64(math.Float32frombits(order.Uint32(b)))
+	case f.float:
+		return math.Float64frombits(order.Uint64(b))
+	case f.bitDepth == 8 && f.signed8:
+		return float64(int8(b[0])) / 128
+	case f.bitDepth == 8:
+		return (float64(b[0]) - 128) / 128
+	case f.bitDepth == 16:
+		return float64(int16(order.Uint16(b))) / (1 << 15)
+	case f.bitDepth == 24:
+		var v int32
+		if f.bigEndian {
+			v = int32(b[0])<<24 | int32(b[1])<<16 | int32(b[2])<<8
+		} else {
+			v = int32(b[2])<<24 | int32(b[1])<<16 | int32(b[0])<<8
+		}
+		return float64(v>>8) / (1 << 23)
+	default:
+		return float64(int32(order.Uint32(b))) / (1 << 31)
+	}
+}
+
+// writeWAV encodes signal as PCM or IEEE float WAV file. Integer samples
+// are clipped to [-1, 1].
+func writeWAV(w io.Writer, s signal.Float64, sampleRate signal.SampleRate, bitDepth int, float bool) error {
+	if !validDepth(bitDepth, float) || bitDepth == 8 {
+		return fmt.Errorf("unsupported bit depth: %d", bitDepth)
+	}
+	tag := uint16(wavFormatPCM)
+	if float {
+		tag = wavFormatFloat
+	}
+	numChannels, sampleSize := s.NumChannels(), bitDepth/8
+	dataSize := s.Size() * numChannels * sampleSize
+	// chunks are word-aligned, odd data is followed by pad byte.
+	pad := dataSize % 2
+	var b bytes.Buffer
+	b.Grow(44 + dataSize + pad)
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(36+dataSize+pad))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, struct {
+		Size          uint32
+		Format        uint16
+		NumChannels   uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+	}{
+		Size:          16,
+		Format:        tag,
+		NumChannels:   uint16(numChannels),
+		SampleRate:    uint32(sampleRate),
+		ByteRate:      uint32(int(sampleRate) * numChannels * sampleSize),
+		BlockAlign:    uint16(numChannels * sampleSize),
+		BitsPerSample: uint16(bitDepth),
+	})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(dataSize))
+	sample := make([]byte, sampleSize)
+	for j := 0; j < s.Size(); j++ {
+		for i := 0; i < numChannels; i++ {
+			encodeSample(sample, s[i][j], bitDepth, float)
+			b.Write(sample)
+		}
+	}
+	if pad != 0 {
+		b.WriteByte(0)
+	}
+	_, err := b.WriteTo(w)
+	return err
+}
+
+func encodeSample(b []byte, v float64, bitDepth int, float bool) {
+	if float {
+		if bitDepth == 32 {
+			binary.LittleEndian.PutUint32(b, math.Float32bits(float32(v)))
+		} else {
+			binary.LittleEndian.PutUint64(b, math.Float64bits(v))
+		}
+		return
+	}
+	v = math.Max(-1, math.Min(1, v))
+	max := float64(int64(1)<<(bitDepth-1) - 1)
+	i := int32(math.Round(v * max))
+	switch bitDepth {
+	case 16:
+		binary.LittleEndian.PutUint16(b, uint16(i))
+	case 24:
+		b[0], b[1], b[2] = byte(i), byte(i>>8), byte(i>>16)
+	default:
+		binary.LittleEndian.PutUint32(b, uint32(i))
+	}
+}
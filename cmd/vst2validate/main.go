@@ -0,0 +1,91 @@
+// Command vst2validate runs conformance checks against VST2 plugins.
+//
+// Usage:
+//
+//	vst2validate [-cycles 10] [-iterations 100] [-seed 0] plugin.vst...
+//
+// Plugin crash terminates the command, the last printed check is the one
+// that crashed.
+//
+// Exit code is 1 if any check failed, 2 if arguments are invalid and 3
+// if plugin can't be opened.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"pipelined.dev/vst2"
+)
+
+// Exit codes.
+const (
+	exitFailed = 1
+	exitUsage  = 2
+	exitPlugin = 3
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes command and returns exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	var (
+		flags     = flag.NewFlagSet("vst2validate", flag.ContinueOnError)
+		v         vst2.Validator
+		tolerance = flags.Float64("tolerance", vst2.DefaultValidationTolerance, "maximum difference of outputs after chunk restore")
+	)
+	flags.IntVar(&v.Cycles, "cycles", vst2.DefaultValidationCycles, "number of open/close cycles")
+	flags.IntVar(&v.Iterations, "iterations", vst2.DefaultValidationIterations, "number of blocks and parameter changes per check")
+	flags.Int64Var(&v.Seed, "seed", 0, "seed of random parameters and noise")
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: vst2validate [flags] plugin...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 || v.Cycles <= 0 || v.Iterations <= 0 || *tolerance < 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	v.Tolerance = *tolerance
+
+	code := 0
+	for _, path := range flags.Args() {
+		lib, err := vst2.Open(path)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			code = exitPlugin
+			continue
+		}
+		report := v.Validate(lib)
+		lib.Close()
+		printReport(stdout, report)
+		if !report.Passed() && code == 0 {
+			code = exitFailed
+		}
+	}
+	return code
+}
+
+func printReport(w io.Writer, r vst2.ValidationReport) {
+	fmt.Fprintln(w, r.Path)
+	for _, result := range r.Results {
+		if result.Err != nil {
+			fmt.Fprintf(w, "  FAIL  %s: %v\n", result.Check, result.Err)
+			continue
+		}
+		fmt.Fprintf(w, "  PASS  %s (%v)\n", result.Check, result.Duration.Round(time.Microsecond))
+	}
+	if r.Passed() {
+		fmt.Fprintln(w, "PASSED")
+	} else {
+		fmt.Fprintln(w, "FAILED")
+	}
+}
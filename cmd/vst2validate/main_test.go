@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	assert.Equal(t, exitUsage, run(nil, io.Discard, io.Discard))
+	assert.Equal(t, exitUsage, run([]string{"-cycles", "0", "plugin.vst"}, io.Discard, io.Discard))
+	assert.Equal(t, exitPlugin, run([]string{"not-exists.vst"}, io.Discard, io.Discard))
+}
+
+func TestPrintReport(t *testing.T) {
+	var b bytes.Buffer
+	printReport(&b, vst2.ValidationReport{
+		Path: "plugin.vst",
+		Results: []vst2.ValidationResult{
+			{Check: "open close"},
+			{Check: "signals", Err: errors.New("sample 0 is NaN")},
+		},
+	})
+	assert.Contains(t, b.String(), "PASS  open close")
+	assert.Contains(t, b.String(), "FAIL  signals: sample 0 is NaN")
+	assert.Contains(t, b.String(), "FAILED")
+}
@@ -0,0 +1,49 @@
+package vst2
+
+// delayLine delays multichannel signal by fixed number of samples.
+type delayLine struct {
+	buf [][]float64
+	pos int
+}
+
+// resize reallocates delay line if delay is changed. Delayed samples are
+// lost in this case.
+func (d *delayLine) resize(numChannels, delay int) {
+	if delay == 0 {
+		d.buf, d.pos = nil, 0
+		return
+	}
+	if len(d.buf) > 0 && len(d.buf[0]) == delay {
+		return
+	}
+	d.buf = make([][]float64, numChannels)
+	for i := range d.buf {
+		d.buf[i] = make([]float64, delay)
+	}
+	d.pos = 0
+}
+
+// process writes delayed samples of channel to output and stores input
+// samples. Input and output can be the same slice. Position should be
+// advanced when all channels are processed.
+func (d *delayLine) process(c int, in, out []float64) {
+	if len(d.buf) == 0 {
+		copy(out, in)
+		return
+	}
+	line, pos := d.buf[c], d.pos
+	for j, v := range in {
+		out[j] = line[pos]
+		line[pos] = v
+		if pos++; pos == len(line) {
+			pos = 0
+		}
+	}
+}
+
+// advance moves delay line position by n samples.
+func (d *delayLine) advance(n int) {
+	if len(d.buf) > 0 {
+		d.pos = (d.pos + n) % len(d.buf[0])
+	}
+}
@@ -0,0 +1,150 @@
+package vst2
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+)
+
+// DefaultMixSmoothing is used if DryWet.Smoothing is not set.
+const DefaultMixSmoothing = 256
+
+// DryWet wraps plugin and blends processed signal with dry input. Dry
+// signal is delayed by plugin's initial delay, so both signals are
+// aligned. Mix and output gain can be changed from any goroutine while
+// processing, changes are smoothed to avoid zipper noise. DryWet owns
+// plugin and closes it on Close. Plugin must be configured and resumed
+// by the caller.
+type DryWet struct {
+	// Smoothing is a time constant of mix and gain changes in samples.
+	// DefaultMixSmoothing is used if zero.
+	Smoothing int
+
+	blockProcessor
+	plugin *Plugin
+	out    DoubleBuffer
+	dry    delayLine
+	// dryBuf holds delayed input of one channel.
+	dryBuf []float64
+
+	// targets are float64 bits, accessed atomically.
+	mix  uint64
+	gain uint64
+	// current values are used only by processing goroutine.
+	currentMix  float64
+	currentGain float64
+	// ramps hold smoothed values for the current block.
+	mixRamp  []float64
+	gainRamp []float64
+}
+
+// NewDryWet allocates buffers for provided dimensions and returns fully
+// wet wrapper with unity gain. Plugin must not have more pins than
+// numChannels.
+func NewDryWet(p *Plugin, numChannels, bufferSize int) (*DryWet, error) {
+	if err := checkPins(p, numChannels); err != nil {
+		return nil, err
+	}
+	d := DryWet{
+		blockProcessor: newBlockProcessor(numChannels, bufferSize, bufferSize),
+		plugin:         p,
+		out:            NewDoubleBuffer(numChannels, bufferSize),
+		dryBuf:         make([]float64, bufferSize),
+		currentMix:     1,
+		currentGain:    1,
+		mixRamp:        make([]float64, bufferSize),
+		gainRamp:       make([]float64, bufferSize),
+	}
+	d.process = d.Process
+	d.SetMix(1)
+	d.SetGain(1)
+	return &d, nil
+}
+
+// SetMix sets the ratio of wet signal: 0 is dry only, 1 is wet only.
+// Value is clamped to [0, 1].
+func (d *DryWet) SetMix(mix float64) {
+	mix = math.Max(0, math.Min(1, mix))
+	atomic.StoreUint64(&d.mix, math.Float64bits(mix))
+}
+
+// Mix returns target ratio of wet signal.
+func (d *DryWet) Mix() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&d.mix))
+}
+
+// SetGain sets linear output gain.
+func (d *DryWet) SetGain(gain float64) {
+	atomic.StoreUint64(&d.gain, math.Float64bits(gain))
+}
+
+// Gain returns target output gain.
+func (d *DryWet) Gain() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&d.gain))
+}
+
+// Plugin returns wrapped plugin.
+func (d *DryWet) Plugin() *Plugin {
+	return d.plugin
+}
+
+// InitialDelay returns latency of wrapper in samples.
+func (d *DryWet) InitialDelay() int {
+	return d.plugin.InitialDelay()
+}
+
+// Process processes first n samples of input buffer and blends result
+// with delayed input. Returned buffer is valid until the next call.
+func (d *DryWet) Process(n int) (DoubleBuffer, error) {
+	if n > d.bufferSize {
+		return DoubleBuffer{}, fmt.Errorf("block size %d exceeds buffer size %d", n, d.bufferSize)
+	}
+	in, out := d.in.slice(n), d.out.slice(n)
+	if err := d.processPlugin(d.plugin, in, out); err != nil {
+		return DoubleBuffer{}, fmt.Errorf("failed to process %s: %w", d.plugin.Name, err)
+	}
+	d.ramp(n)
+	d.dry.resize(d.numChannels, d.plugin.InitialDelay())
+	dry, mixes, gains := d.dryBuf[:n], d.mixRamp[:n], d.gainRamp[:n]
+	for i := 0; i < d.numChannels; i++ {
+		d.dry.process(i, in.Channel(i), dry)
+		wet := out.Channel(i)
+		for j := range wet {
+			wet[j] = gains[j] * (mixes[j]*wet[j] + (1-mixes[j])*dry[j])
+		}
+	}
+	d.dry.advance(n)
+	return out, nil
+}
+
+// ramp fills smoothed values of mix and gain for n samples.
+func (d *DryWet) ramp(n int) {
+	smoothing := d.Smoothing
+	if smoothing == 0 {
+		smoothing = DefaultMixSmoothing
+	}
+	coef := 1 - math.Exp(-1/float64(smoothing))
+	d.currentMix = smooth(d.mixRamp[:n], d.currentMix, d.Mix(), coef)
+	d.currentGain = smooth(d.gainRamp[:n], d.currentGain, d.Gain(), coef)
+}
+
+// smooth fills ramp with one-pole smoothed values and returns the last
+// one. Value snaps to target when it's close enough.
+func smooth(ramp []float64, current, target, coef float64) float64 {
+	for i := range ramp {
+		if math.Abs(target-current) < 1e-6 {
+			current = target
+		} else {
+			current += (target - current) * coef
+		}
+		ramp[i] = current
+	}
+	return current
+}
+
+// Close closes plugin and frees buffers.
+func (d *DryWet) Close() error {
+	d.out.Free()
+	d.out = DoubleBuffer{}
+	return d.close(d.plugin)
+}
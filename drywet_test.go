@@ -0,0 +1,36 @@
+package vst2_test
+
+import (
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryWet(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	const bufferSize = 64
+	d, err := vst2.NewDryWet(loadResumed(t, v, bufferSize), 2, bufferSize)
+	require.Nil(t, err)
+	defer d.Close()
+	d.Plugin().SetParameter(0, 0.5)
+	d.Smoothing = 8
+	d.SetMix(0.5)
+	d.SetGain(2)
+	assert.Equal(t, 0.5, d.Mix())
+
+	s := signal.Float64Buffer(2, 200)
+	for i := range s[0] {
+		s[0][i], s[1][i] = 1, 1
+	}
+	require.Nil(t, d.ProcessFloat64(s))
+	// smoothing starts from fully wet signal with unity gain.
+	assert.InDelta(t, 0.5, s[0][0], 0.2)
+	assert.InDelta(t, 1.5, s[0][199], 1e-6)
+}
@@ -55,16 +55,11 @@ func main() {
 
 	// Set sample rate in Herz.
 	plugin.SetSampleRate(44100)
-	// Set channels information.
+	// Set channels information. Arrangement is derived from number of
+	// channels in data.
 	plugin.SetSpeakerArrangement(
-		&vst2.SpeakerArrangement{
-			Type:        vst2.SpeakerArrMono,
-			NumChannels: int32(data.NumChannels()),
-		},
-		&vst2.SpeakerArrangement{
-			Type:        vst2.SpeakerArrMono,
-			NumChannels: int32(data.NumChannels()),
-		},
+		vst2.DefaultSpeakerArrangement(data.NumChannels()),
+		vst2.DefaultSpeakerArrangement(data.NumChannels()),
 	)
 	// Set buffer size.
 	plugin.SetBufferSize(data.Size())
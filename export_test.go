@@ -0,0 +1,12 @@
+package vst2
+
+// ProcessorBuffers returns addresses of processor C buffers, so tests can
+// check that they aren't reallocated while processing.
+func ProcessorBuffers(p *Processor) (in, out uintptr) {
+	return uintptr(p.doubleIn.mem), uintptr(p.doubleOut.mem)
+}
+
+// PluginCallback returns host callback registered for plugin.
+func PluginCallback(p *Plugin) HostCallbackFunc {
+	return callbacks.Load().([]HostCallbackFunc)[p.callback]
+}
@@ -0,0 +1,301 @@
+package vst2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// NodeID identifies node of the graph.
+type NodeID int
+
+const (
+	// GraphInput is a node that provides graph input.
+	GraphInput NodeID = iota
+	// GraphOutput is a node that sums signals into graph output.
+	GraphOutput
+)
+
+// ErrCycle is returned when connection would create a cycle in graph.
+var ErrCycle = errors.New("connection creates a cycle")
+
+type (
+	// Graph is a directed acyclic graph of plugins. Every node sums
+	// signals of incoming edges multiplied by their gains and processes
+	// the sum with plugin. Branches with different latency are aligned
+	// with delay lines, so all signals arrive to the node in sync. Graph
+	// owns plugins and closes them on Close. Plugins must be configured
+	// and resumed by the caller.
+	Graph struct {
+		blockProcessor
+		nodes []*graphNode
+		edges []*graphEdge
+		// order is a topological order of nodes.
+		order []NodeID
+		dirty bool
+	}
+
+	graphNode struct {
+		plugin *Plugin
+		// in contains sum of incoming signals.
+		in  DoubleBuffer
+		out DoubleBuffer
+		// latency of node output relative to graph input.
+		latency int
+	}
+
+	graphEdge struct {
+		from, to NodeID
+		gain     float64
+		delay    delayLine
+	}
+)
+
+// NewGraph returns graph that contains only input and output nodes.
+func NewGraph(numChannels, bufferSize int) *Graph {
+	g := Graph{
+		blockProcessor: newBlockProcessor(numChannels, bufferSize, bufferSize),
+		dirty:          true,
+	}
+	g.process = g.Process
+	g.nodes = append(g.nodes, &graphNode{in: g.in, out: g.in})
+	output := NewDoubleBuffer(numChannels, bufferSize)
+	g.nodes = append(g.nodes, &graphNode{in: output, out: output})
+	return &g
+}
+
+// AddNode adds plugin node to the graph. Plugin must not have more pins
+// than graph has channels.
+func (g *Graph) AddNode(p *Plugin) (NodeID, error) {
+	if err := checkPins(p, g.numChannels); err != nil {
+		return 0, err
+	}
+	g.nodes = append(g.nodes, &graphNode{
+		plugin: p,
+		in:     NewDoubleBuffer(g.numChannels, g.bufferSize),
+		out:    NewDoubleBuffer(g.numChannels, g.bufferSize),
+	})
+	g.dirty = true
+	return NodeID(len(g.nodes) - 1), nil
+}
+
+// Plugin returns plugin of the node. Nil is returned for input and
+// output nodes.
+func (g *Graph) Plugin(id NodeID) *Plugin {
+	return g.nodes[id].plugin
+}
+
+// Connect adds edge that sends signal of one node to another with
+// provided gain. If nodes are already connected, gain is updated.
+// ErrCycle is returned if connection creates a cycle.
+func (g *Graph) Connect(from, to NodeID, gain float64) error {
+	if err := g.validate(from, to); err != nil {
+		return err
+	}
+	if from == GraphOutput || to == GraphInput {
+		return fmt.Errorf("cannot connect %d to %d: output can't be sent and input can't be received", from, to)
+	}
+	if e := g.edge(from, to); e != nil {
+		e.gain = gain
+		return nil
+	}
+	if from == to || g.reachable(to, from) {
+		return fmt.Errorf("cannot connect %d to %d: %w", from, to, ErrCycle)
+	}
+	g.edges = append(g.edges, &graphEdge{from: from, to: to, gain: gain})
+	g.dirty = true
+	return nil
+}
+
+// Disconnect removes edge between nodes.
+func (g *Graph) Disconnect(from, to NodeID) {
+	for i, e := range g.edges {
+		if e.from == from && e.to == to {
+			g.edges = append(g.edges[:i], g.edges[i+1:]...)
+			g.dirty = true
+			return
+		}
+	}
+}
+
+// SetGain sets gain of the edge.
+func (g *Graph) SetGain(from, to NodeID, gain float64) error {
+	e := g.edge(from, to)
+	if e == nil {
+		return fmt.Errorf("nodes %d and %d are not connected", from, to)
+	}
+	e.gain = gain
+	return nil
+}
+
+// Latency returns latency of graph output in samples.
+func (g *Graph) Latency() int {
+	g.align()
+	return g.nodes[GraphOutput].latency
+}
+
+// Process evaluates first n samples of input buffer through the graph.
+// Returned buffer contains output and is valid until the next call.
+func (g *Graph) Process(n int) (DoubleBuffer, error) {
+	if n > g.bufferSize {
+		return DoubleBuffer{}, fmt.Errorf("block size %d exceeds graph buffer size %d", n, g.bufferSize)
+	}
+	g.align()
+	for _, id := range g.order {
+		if id == GraphInput {
+			continue
+		}
+		node := g.nodes[id]
+		in := node.in.slice(n)
+		for i := 0; i < in.numChannels; i++ {
+			ch := in.Channel(i)
+			for j := range ch {
+				ch[j] = 0
+			}
+		}
+		for _, e := range g.edges {
+			if e.to == id {
+				e.sum(g.nodes[e.from].out.slice(n), in)
+			}
+		}
+		if node.plugin == nil {
+			continue
+		}
+		if err := g.processPlugin(node.plugin, in, node.out.slice(n)); err != nil {
+			return DoubleBuffer{}, fmt.Errorf("failed to process %s: %w", node.plugin.Name, err)
+		}
+	}
+	return g.nodes[GraphOutput].out.slice(n), nil
+}
+
+// Close closes all plugins and frees buffers.
+func (g *Graph) Close() error {
+	var plugins []*Plugin
+	for i, node := range g.nodes {
+		// input node buffer is freed with block processor.
+		if NodeID(i) == GraphInput {
+			continue
+		}
+		node.in.Free()
+		if NodeID(i) == GraphOutput {
+			continue
+		}
+		node.out.Free()
+		plugins = append(plugins, node.plugin)
+	}
+	g.nodes, g.edges, g.order = nil, nil, nil
+	return g.close(plugins...)
+}
+
+func (g *Graph) validate(ids ...NodeID) error {
+	for _, id := range ids {
+		if id < 0 || int(id) >= len(g.nodes) {
+			return fmt.Errorf("node %d doesn't exist", id)
+		}
+	}
+	return nil
+}
+
+func (g *Graph) edge(from, to NodeID) *graphEdge {
+	for _, e := range g.edges {
+		if e.from == from && e.to == to {
+			return e
+		}
+	}
+	return nil
+}
+
+// reachable returns true if there is a path between nodes.
+func (g *Graph) reachable(from, to NodeID) bool {
+	visited := make([]bool, len(g.nodes))
+	stack := []NodeID{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == to {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		for _, e := range g.edges {
+			if e.from == id {
+				stack = append(stack, e.to)
+			}
+		}
+	}
+	return false
+}
+
+// align sorts nodes topologically and updates latencies and delay lines
+// of edges. Plugins can change their latency, so it's checked on every
+// call.
+func (g *Graph) align() {
+	if g.dirty {
+		g.sort()
+		g.dirty = false
+	}
+	for _, id := range g.order {
+		node := g.nodes[id]
+		node.latency = 0
+		for _, e := range g.edges {
+			if e.to == id && g.nodes[e.from].latency > node.latency {
+				node.latency = g.nodes[e.from].latency
+			}
+		}
+		for _, e := range g.edges {
+			if e.to == id {
+				e.delay.resize(g.numChannels, node.latency-g.nodes[e.from].latency)
+			}
+		}
+		if node.plugin != nil {
+			node.latency += node.plugin.InitialDelay()
+		}
+	}
+}
+
+// sort computes topological order of nodes with Kahn's algorithm.
+func (g *Graph) sort() {
+	degrees := make([]int, len(g.nodes))
+	for _, e := range g.edges {
+		degrees[e.to]++
+	}
+	g.order = g.order[:0]
+	for id := range g.nodes {
+		if degrees[id] == 0 {
+			g.order = append(g.order, NodeID(id))
+		}
+	}
+	for i := 0; i < len(g.order); i++ {
+		for _, e := range g.edges {
+			if e.from != g.order[i] {
+				continue
+			}
+			if degrees[e.to]--; degrees[e.to] == 0 {
+				g.order = append(g.order, e.to)
+			}
+		}
+	}
+}
+
+// sum adds delayed signal multiplied by gain to destination.
+func (e *graphEdge) sum(src, dst DoubleBuffer) {
+	for i := 0; i < dst.numChannels; i++ {
+		in, out := src.Channel(i), dst.Channel(i)
+		if len(e.delay.buf) == 0 {
+			for j, v := range in {
+				out[j] += e.gain * v
+			}
+			continue
+		}
+		line, pos := e.delay.buf[i], e.delay.pos
+		for j, v := range in {
+			out[j] += e.gain * line[pos]
+			line[pos] = v
+			if pos++; pos == len(line) {
+				pos = 0
+			}
+		}
+	}
+	e.delay.advance(src.size)
+}
@@ -0,0 +1,44 @@
+package vst2_test
+
+import (
+	"errors"
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	const bufferSize = 64
+	g := vst2.NewGraph(2, bufferSize)
+	defer g.Close()
+	wet, err := g.AddNode(loadResumed(t, v, bufferSize))
+	require.Nil(t, err)
+	g.Plugin(wet).SetParameter(0, 0.5)
+
+	// parallel dry and wet paths.
+	require.Nil(t, g.Connect(vst2.GraphInput, wet, 1))
+	require.Nil(t, g.Connect(wet, vst2.GraphOutput, 1))
+	require.Nil(t, g.Connect(vst2.GraphInput, vst2.GraphOutput, 0.25))
+	assert.True(t, errors.Is(g.Connect(wet, wet, 1), vst2.ErrCycle))
+	assert.Equal(t, g.Plugin(wet).InitialDelay(), g.Latency())
+
+	s := signal.Float64{make([]float64, 100), make([]float64, 100)}
+	delay := g.Latency()
+	s[0][0] = 1
+	require.Nil(t, g.ProcessFloat64(s))
+	assert.InDelta(t, 0.75, s[0][delay], 1e-6)
+
+	require.Nil(t, g.SetGain(vst2.GraphInput, vst2.GraphOutput, 0))
+	s = signal.Float64{make([]float64, 100), make([]float64, 100)}
+	s[0][0] = 1
+	require.Nil(t, g.ProcessFloat64(s))
+	assert.InDelta(t, 0.5, s[0][delay], 1e-6)
+}
@@ -0,0 +1,311 @@
+package vst2
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include "vst.h"
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"pipelined.dev/signal"
+)
+
+const (
+	// DefaultOfflineBufferSize is used if Offline.BufferSize is not set.
+	DefaultOfflineBufferSize = 4096
+	// DefaultOfflinePasses is used if Offline.MaxPasses is not set.
+	DefaultOfflinePasses = 16
+)
+
+// ErrOfflineNotSupported is returned if plugin doesn't start offline
+// processing when it's notified about files.
+var ErrOfflineNotSupported = errors.New("offline processing not supported")
+
+type (
+	// OfflineFile is an in-memory audio file served to plugin during
+	// offline processing.
+	OfflineFile struct {
+		Name       string
+		SampleRate signal.SampleRate
+		Flags      AudioFileFlags
+		// Source contains original samples.
+		Source signal.Float64
+		// Output contains samples written by plugin.
+		Output signal.Float64
+	}
+
+	// Offline is a host-side engine for offline processing. It serves
+	// HostOffline* calls of plugin with data of in-memory files. Plugin
+	// should be loaded with callback returned by Offline.Callback.
+	Offline struct {
+		Files []*OfflineFile
+		// BufferSize is a size of task buffers in frames.
+		// DefaultOfflineBufferSize is used if zero.
+		BufferSize int
+		// MaxPasses limits the number of passes plugin can request.
+		// DefaultOfflinePasses is used if zero.
+		MaxPasses int
+
+		running bool
+		pass    int
+		// started is set when plugin requests processing start.
+		started bool
+		// selected are indexes of files requested by plugin for the
+		// next pass.
+		selected []int
+		// tasks of the current pass, one per file.
+		tasks []C.OfflineTask
+		// files of the current pass. Plugin can select files for the
+		// next pass while current one is running.
+		files []*OfflineFile
+		// bufferSize of the current pass tasks.
+		bufferSize int
+	}
+)
+
+// Callback returns host callback that handles offline calls and passes
+// the rest to provided callback.
+func (o *Offline) Callback(c HostCallbackFunc) HostCallbackFunc {
+	return func(opcode HostOpcode, index Index, value Value, ptr Ptr, opt Opt) Return {
+		switch opcode {
+		case HostOfflineStart:
+			return o.start(int(value), ptr)
+		case HostOfflineRead:
+			return o.read((*C.OfflineTask)(ptr), OfflineOption(value), index != 0)
+		case HostOfflineWrite:
+			return o.write((*C.OfflineTask)(ptr), OfflineOption(value))
+		case HostOfflineGetCurrentPass:
+			return Return(o.pass)
+		case HostOfflineGetCurrentMetaPass:
+			return 0
+		case HostGetCurrentProcessLevel:
+			if o.running {
+				return Return(ProcessLevelOffline)
+			}
+		}
+		if c == nil {
+			return 0
+		}
+		return c(opcode, index, value, ptr, opt)
+	}
+}
+
+// Run notifies plugin about available files and executes tasks while
+// plugin requests new passes.
+func (o *Offline) Run(p *Plugin) error {
+	numFiles := len(o.Files)
+	if numFiles == 0 {
+		return fmt.Errorf("no files for offline processing")
+	}
+	mem := C.calloc(C.size_t(numFiles), C.sizeof_AudioFile)
+	if mem == nil {
+		return fmt.Errorf("failed to allocate %d offline files", numFiles)
+	}
+	defer C.free(mem)
+	files := unsafe.Slice((*C.AudioFile)(mem), numFiles)
+	for i, f := range o.Files {
+		f.fill(&files[i], i)
+	}
+
+	o.running = true
+	defer func() { o.running = false }()
+	p.Dispatch(EffOfflineNotify, 1, Value(numFiles), Ptr(unsafe.Pointer(&files[0])), 0)
+	defer p.Dispatch(EffOfflineNotify, 0, Value(numFiles), Ptr(unsafe.Pointer(&files[0])), 0)
+	if !o.started {
+		return fmt.Errorf("plugin %s didn't start offline processing: %w", p.Name, ErrOfflineNotSupported)
+	}
+
+	maxPasses := o.MaxPasses
+	if maxPasses == 0 {
+		maxPasses = DefaultOfflinePasses
+	}
+	for o.pass = 0; o.started; o.pass++ {
+		if o.pass == maxPasses {
+			return fmt.Errorf("plugin %s exceeded %d offline passes", p.Name, maxPasses)
+		}
+		o.started = false
+		if err := o.runPass(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPass prepares and runs tasks for selected files.
+func (o *Offline) runPass(p *Plugin) error {
+	bufferSize := o.BufferSize
+	if bufferSize == 0 {
+		bufferSize = DefaultOfflineBufferSize
+	}
+	numTasks := len(o.selected)
+	if numTasks == 0 {
+		return nil
+	}
+	mem := C.calloc(C.size_t(numTasks), C.sizeof_OfflineTask)
+	if mem == nil {
+		return fmt.Errorf("failed to allocate %d offline tasks", numTasks)
+	}
+	tasks := unsafe.Slice((*C.OfflineTask)(mem), numTasks)
+	files := make([]*OfflineFile, 0, numTasks)
+	for i, fi := range o.selected {
+		o.Files[fi].task(&tasks[i], bufferSize)
+		files = append(files, o.Files[fi])
+	}
+	o.tasks, o.files, o.bufferSize = tasks, files, bufferSize
+	defer func() {
+		for i := range tasks {
+			freeFloatChannels(tasks[i].inputBuffer, int(tasks[i].numSourceChannels))
+			freeFloatChannels(tasks[i].outputBuffer, int(tasks[i].numDestinationChannels))
+		}
+		C.free(mem)
+		o.tasks, o.files = nil, nil
+	}()
+
+	p.Dispatch(EffOfflinePrepare, 0, Value(numTasks), Ptr(unsafe.Pointer(&tasks[0])), 0)
+	p.Dispatch(EffOfflineRun, 0, Value(numTasks), Ptr(unsafe.Pointer(&tasks[0])), 0)
+	for i := range tasks {
+		if OfflineTaskFlags(tasks[i].flags)&OfflinePlugError != 0 {
+			return fmt.Errorf("plugin %s failed offline task: %s", p.Name, C.GoString(&tasks[i].outputText[0]))
+		}
+	}
+	return nil
+}
+
+// start handles HostOfflineStart call. Plugin passes files it wants to
+// process, unique id contains file index. Plugin can't select more
+// files than it was notified about.
+func (o *Offline) start(numFiles int, ptr Ptr) Return {
+	if ptr == nil || numFiles <= 0 || numFiles > len(o.Files) {
+		return 0
+	}
+	files := unsafe.Slice((*C.AudioFile)(ptr), numFiles)
+	o.selected = o.selected[:0]
+	for i := range files {
+		fi := int(files[i].uniqueId)
+		if fi < 0 || fi >= len(o.Files) {
+			return 0
+		}
+		o.Files[fi].Flags = AudioFileFlags(files[i].flags)
+		o.selected = append(o.selected, fi)
+	}
+	o.started = true
+	return 1
+}
+
+// read handles HostOfflineRead call. Task fields are set by plugin, so
+// they're validated against allocated buffers.
+func (o *Offline) read(task *C.OfflineTask, option OfflineOption, readSource bool) Return {
+	if task == nil || option != OfflineAudio {
+		return 0
+	}
+	f := o.taskFile(task)
+	if f == nil {
+		return 0
+	}
+	data := f.Output
+	if readSource {
+		data = f.Source
+	}
+	pos := int(task.readPosition)
+	if pos < 0 || pos > data.Size() || task.numSourceChannels < 0 {
+		return 0
+	}
+	count := min(int(task.readCount), o.bufferSize)
+	count = min(count, data.Size()-pos)
+	if count < 0 {
+		count = 0
+	}
+	numChannels := min(int(task.numSourceChannels), min(data.NumChannels(), f.Source.NumChannels()))
+	channels := floatChannels(task.inputBuffer, numChannels)
+	for c := range channels {
+		row := (*[1 << 30]C.float)(unsafe.Pointer(channels[c]))
+		for i := 0; i < count; i++ {
+			row[i] = C.float(data[c][pos+i])
+		}
+	}
+	task.readCount = C.int32_t(count)
+	return 1
+}
+
+// write handles HostOfflineWrite call. Task fields are validated like in
+// read.
+func (o *Offline) write(task *C.OfflineTask, option OfflineOption) Return {
+	if task == nil || option != OfflineAudio {
+		return 0
+	}
+	f := o.taskFile(task)
+	if f == nil {
+		return 0
+	}
+	numChannels := int(task.numDestinationChannels)
+	pos := int(task.writePosition)
+	if pos < 0 || numChannels < 0 || numChannels > f.Source.NumChannels() {
+		return 0
+	}
+	count := min(int(task.writeCount), o.bufferSize)
+	if count <= 0 {
+		return 1
+	}
+	// grow output to fit written frames.
+	if f.Output.NumChannels() != numChannels {
+		f.Output = signal.Float64Buffer(numChannels, 0)
+	}
+	if size := f.Output.Size(); size < pos+count {
+		f.Output = f.Output.Append(signal.Float64Buffer(numChannels, pos+count-size))
+	}
+	channels := floatChannels(task.outputBuffer, numChannels)
+	for c := range channels {
+		row := (*[1 << 30]C.float)(unsafe.Pointer(channels[c]))
+		for i := 0; i < count; i++ {
+			f.Output[c][pos+i] = float64(row[i])
+		}
+	}
+	return 1
+}
+
+// taskFile returns file referenced by task of the current pass.
+func (o *Offline) taskFile(task *C.OfflineTask) *OfflineFile {
+	if len(o.tasks) == 0 {
+		return nil
+	}
+	offset := uintptr(unsafe.Pointer(task)) - uintptr(unsafe.Pointer(&o.tasks[0]))
+	i := int(offset / C.sizeof_OfflineTask)
+	if offset%C.sizeof_OfflineTask != 0 || i >= len(o.tasks) {
+		return nil
+	}
+	return o.files[i]
+}
+
+// fill populates C audio file structure.
+func (f *OfflineFile) fill(af *C.AudioFile, index int) {
+	af.flags = C.int32_t(f.Flags)
+	name := (*[maxFileNameLen]byte)(unsafe.Pointer(&af.name[0]))
+	copy(name[:maxFileNameLen-1], f.Name)
+	af.uniqueId = C.int32_t(index)
+	af.sampleRate = C.double(f.SampleRate)
+	af.numChannels = C.int32_t(f.Source.NumChannels())
+	af.numFrames = C.double(f.Source.Size())
+	af.editCursorPosition = -1
+	af.selectionStart = -1
+	af.timeSigNumerator = 4
+	af.timeSigDenominator = 4
+}
+
+// task populates C offline task structure and allocates its buffers.
+func (f *OfflineFile) task(t *C.OfflineTask, bufferSize int) {
+	numChannels := f.Source.NumChannels()
+	t.sizeInputBuffer = C.int32_t(bufferSize)
+	t.sizeOutputBuffer = C.int32_t(bufferSize)
+	t.inputBuffer = newFloatChannels(numChannels, bufferSize)
+	t.outputBuffer = newFloatChannels(numChannels, bufferSize)
+	t.numFramesToProcess = C.double(f.Source.Size())
+	t.numFramesInSourceFile = C.double(f.Source.Size())
+	t.sourceSampleRate = C.double(f.SampleRate)
+	t.destinationSampleRate = C.double(f.SampleRate)
+	t.numSourceChannels = C.int32_t(numChannels)
+	t.numDestinationChannels = C.int32_t(numChannels)
+}
@@ -0,0 +1,49 @@
+package vst2_test
+
+import (
+	"errors"
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffline(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	newOffline := func(maxPasses int) *vst2.Offline {
+		return &vst2.Offline{
+			BufferSize: 3,
+			MaxPasses:  maxPasses,
+			Files: []*vst2.OfflineFile{
+				{
+					Name:       "test",
+					SampleRate: sampleRate,
+					Source:     signal.Float64{{1, 2, 3, 4, 5, 6, 7}, {1, 1, 1, 1, 1, 1, 1}},
+				},
+			},
+		}
+	}
+
+	o := newOffline(0)
+	p := v.Load(o.Callback(nil))
+	defer p.Close()
+	err = o.Run(p)
+	if errors.Is(err, vst2.ErrOfflineNotSupported) {
+		t.Skip("plugin doesn't support offline processing")
+	}
+	require.Nil(t, err)
+	// test plugin requests the second pass and multiplies source by
+	// number of the pass, starting from one.
+	assert.Equal(t, signal.Float64{{2, 4, 6, 8, 10, 12, 14}, {2, 2, 2, 2, 2, 2, 2}}, o.Files[0].Output)
+
+	limited := newOffline(1)
+	p = v.Load(limited.Callback(nil))
+	defer p.Close()
+	assert.NotNil(t, limited.Run(p), "passes limit exceeded")
+}
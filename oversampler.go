@@ -0,0 +1,224 @@
+package vst2
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import (
+	"fmt"
+	"math"
+	"unsafe"
+
+	"pipelined.dev/signal"
+)
+
+// oversamplingTaps is a number of filter taps per polyphase branch.
+// Filters add oversamplingTaps samples of latency at host rate.
+const oversamplingTaps = 16
+
+// Oversampler wraps plugin and runs it at multiple of host sample rate.
+// Input is upsampled and output is downsampled with polyphase FIR
+// filters. Oversampler owns plugin and closes it on Close. Plugin is
+// configured by NewOversampler and must be resumed by the caller after
+// that. Host callback of plugin is wrapped, so sample rate, block size
+// and time info are reported at plugin rate.
+type Oversampler struct {
+	// input is at host rate, float buffers are at plugin rate.
+	blockProcessor
+	plugin *Plugin
+	factor int
+	// out is at host rate.
+	out DoubleBuffer
+	// upIn and upOut are at plugin rate.
+	upIn  DoubleBuffer
+	upOut DoubleBuffer
+	// coefs is a lowpass filter, padded to multiple of factor.
+	coefs []float64
+	// upHistory holds previous input samples followed by current block.
+	upHistory [][]float64
+	// downHistory holds previous plugin output samples followed by
+	// current block.
+	downHistory [][]float64
+	// timeInfo is C-allocated copy of host time info at plugin rate.
+	timeInfo *TimeInfo
+}
+
+// NewOversampler sets sample rate and buffer size of plugin multiplied
+// by factor. Factor must be 2, 4 or 8. Plugin must be suspended and must
+// not have more pins than numChannels.
+func NewOversampler(p *Plugin, factor, numChannels, bufferSize int, sampleRate signal.SampleRate) (*Oversampler, error) {
+	switch factor {
+	case 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("unsupported oversampling factor: %d", factor)
+	}
+	if err := checkPins(p, numChannels); err != nil {
+		return nil, err
+	}
+	if err := p.SetSampleRate(int(sampleRate) * factor); err != nil {
+		return nil, fmt.Errorf("failed to set sample rate: %w", err)
+	}
+	if err := p.SetBufferSize(bufferSize * factor); err != nil {
+		return nil, fmt.Errorf("failed to set buffer size: %w", err)
+	}
+	coefs := lowpass(oversamplingTaps*factor+1, 0.45/float64(factor))
+	// pad filter, so every polyphase branch has the same length.
+	coefs = append(coefs, make([]float64, factor-1)...)
+	o := Oversampler{
+		blockProcessor: newBlockProcessor(numChannels, bufferSize, bufferSize*factor),
+		plugin:         p,
+		factor:         factor,
+		out:            NewDoubleBuffer(numChannels, bufferSize),
+		upIn:           NewDoubleBuffer(numChannels, bufferSize*factor),
+		upOut:          NewDoubleBuffer(numChannels, bufferSize*factor),
+		coefs:          coefs,
+		upHistory:      make([][]float64, numChannels),
+		downHistory:    make([][]float64, numChannels),
+		timeInfo:       (*TimeInfo)(C.calloc(1, C.size_t(unsafe.Sizeof(TimeInfo{})))),
+	}
+	o.process = o.Process
+	for i := 0; i < numChannels; i++ {
+		o.upHistory[i] = make([]float64, o.phaseLen()-1+bufferSize)
+		o.downHistory[i] = make([]float64, len(coefs)-1+bufferSize*factor)
+	}
+	wrapCallback(p.callback, o.callback)
+	return &o, nil
+}
+
+// callback returns host callback that scales sample rate, block size and
+// sample position reported by provided callback to plugin rate. Time info
+// is copied, so host's one isn't modified.
+func (o *Oversampler) callback(c HostCallbackFunc) HostCallbackFunc {
+	return func(opcode HostOpcode, index Index, value Value, ptr Ptr, opt Opt) Return {
+		if c == nil {
+			return 0
+		}
+		r := c(opcode, index, value, ptr, opt)
+		switch opcode {
+		case HostGetSampleRate, HostGetBlockSize:
+			return r * Return(o.factor)
+		case HostGetTime:
+			if r == 0 {
+				return 0
+			}
+			*o.timeInfo = *r.timeInfo()
+			o.timeInfo.SampleRate *= float64(o.factor)
+			o.timeInfo.SamplePos *= float64(o.factor)
+			return o.timeInfo.Return()
+		}
+		return r
+	}
+}
+
+// lowpass returns Blackman-windowed sinc filter with cutoff relative to
+// sample rate. Filter has unity gain at DC.
+func lowpass(n int, cutoff float64) []float64 {
+	coefs := make([]float64, n)
+	center := float64(n-1) / 2
+	var sum float64
+	for i := range coefs {
+		x := float64(i) - center
+		v := 2 * cutoff
+		if x != 0 {
+			v = math.Sin(2*math.Pi*cutoff*x) / (math.Pi * x)
+		}
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n-1)) + 0.08*math.Cos(4*math.Pi*float64(i)/float64(n-1))
+		coefs[i] = v * w
+		sum += coefs[i]
+	}
+	for i := range coefs {
+		coefs[i] /= sum
+	}
+	return coefs
+}
+
+// phaseLen returns number of coefficients in polyphase branch.
+func (o *Oversampler) phaseLen() int {
+	return len(o.coefs) / o.factor
+}
+
+// Plugin returns wrapped plugin.
+func (o *Oversampler) Plugin() *Plugin {
+	return o.plugin
+}
+
+// Factor returns oversampling factor.
+func (o *Oversampler) Factor() int {
+	return o.factor
+}
+
+// InitialDelay returns latency at host rate in samples. It includes
+// delay of filters and rounded up latency of plugin.
+func (o *Oversampler) InitialDelay() int {
+	return oversamplingTaps + (o.plugin.InitialDelay()+o.factor-1)/o.factor
+}
+
+// Process upsamples first n samples of input buffer, processes them
+// with plugin and downsamples the result. Returned buffer is valid until
+// the next call.
+func (o *Oversampler) Process(n int) (DoubleBuffer, error) {
+	if n > o.bufferSize {
+		return DoubleBuffer{}, fmt.Errorf("block size %d exceeds buffer size %d", n, o.bufferSize)
+	}
+	upIn, upOut := o.upIn.slice(n*o.factor), o.upOut.slice(n*o.factor)
+	for i := 0; i < o.numChannels; i++ {
+		o.upsample(i, o.in.slice(n).Channel(i), upIn.Channel(i))
+	}
+	if err := o.processPlugin(o.plugin, upIn, upOut); err != nil {
+		return DoubleBuffer{}, fmt.Errorf("failed to process %s: %w", o.plugin.Name, err)
+	}
+	out := o.out.slice(n)
+	for i := 0; i < o.numChannels; i++ {
+		o.downsample(i, upOut.Channel(i), out.Channel(i))
+	}
+	return out, nil
+}
+
+// upsample interpolates channel with polyphase filter. Every input
+// sample produces factor output samples, one per filter branch.
+func (o *Oversampler) upsample(c int, in, out []float64) {
+	history := o.upHistory[c]
+	offset := o.phaseLen() - 1
+	copy(history[offset:], in)
+	gain := float64(o.factor)
+	for j := range in {
+		for p := 0; p < o.factor; p++ {
+			var v float64
+			for m := 0; m < o.phaseLen(); m++ {
+				v += o.coefs[p+m*o.factor] * history[offset+j-m]
+			}
+			out[j*o.factor+p] = gain * v
+		}
+	}
+	// keep the tail for the next block.
+	copy(history, history[len(in):len(in)+offset])
+}
+
+// downsample filters channel and keeps every factor-th sample. Filter
+// is evaluated only for kept samples.
+func (o *Oversampler) downsample(c int, in, out []float64) {
+	history := o.downHistory[c]
+	offset := len(o.coefs) - 1
+	copy(history[offset:], in)
+	for j := range out {
+		t := offset + j*o.factor
+		var v float64
+		for i, coef := range o.coefs {
+			v += coef * history[t-i]
+		}
+		out[j] = v
+	}
+	copy(history, history[len(in):len(in)+offset])
+}
+
+// Close closes plugin and frees buffers.
+func (o *Oversampler) Close() error {
+	for _, b := range []*DoubleBuffer{&o.out, &o.upIn, &o.upOut} {
+		b.Free()
+		*b = DoubleBuffer{}
+	}
+	err := o.close(o.plugin)
+	C.free(unsafe.Pointer(o.timeInfo))
+	o.timeInfo = nil
+	return err
+}
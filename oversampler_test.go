@@ -0,0 +1,79 @@
+package vst2_test
+
+import (
+	"math"
+	"testing"
+	"unsafe"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOversampler(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	unsupported := v.Load(testHostCallback())
+	defer unsupported.Close()
+	_, err = vst2.NewOversampler(unsupported, 3, 2, 64, sampleRate)
+	assert.NotNil(t, err)
+
+	for _, factor := range []int{2, 4, 8} {
+		p := v.Load(testHostCallback())
+		o, err := vst2.NewOversampler(p, factor, 2, 64, sampleRate)
+		require.Nil(t, err)
+		require.Nil(t, p.Start())
+		require.Nil(t, p.SetParameter(0, 1))
+
+		const size = 1000
+		s := signal.Float64Buffer(2, size)
+		for i := range s[0] {
+			s[0][i] = math.Sin(2 * math.Pi * 1000 * float64(i) / sampleRate)
+		}
+		in := append([]float64(nil), s[0]...)
+		require.Nil(t, o.ProcessFloat64(s))
+		delay := o.InitialDelay()
+		for i := 200; i < size; i++ {
+			assert.InDelta(t, in[i-delay], s[0][i], 1e-3, "factor %d sample %d", factor, i)
+		}
+		require.Nil(t, o.Close())
+	}
+}
+
+func TestOversamplerCallback(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	var ti *vst2.TimeInfo
+	p := v.Load(func(opcode vst2.HostOpcode, index vst2.Index, value vst2.Value, ptr vst2.Ptr, opt vst2.Opt) vst2.Return {
+		switch opcode {
+		case vst2.HostGetSampleRate:
+			return sampleRate
+		case vst2.HostGetBlockSize:
+			return 64
+		case vst2.HostGetTime:
+			return ti.Return()
+		}
+		return 0
+	})
+	ti = p.TimeInfo()
+	ti.SampleRate, ti.SamplePos = sampleRate, 100
+	o, err := vst2.NewOversampler(p, 4, 2, 64, sampleRate)
+	require.Nil(t, err)
+	defer o.Close()
+
+	c := vst2.PluginCallback(p)
+	assert.Equal(t, vst2.Return(4*sampleRate), c(vst2.HostGetSampleRate, 0, 0, nil, 0))
+	assert.Equal(t, vst2.Return(4*64), c(vst2.HostGetBlockSize, 0, 0, nil, 0))
+	r := c(vst2.HostGetTime, 0, 0, nil, 0)
+	assert.NotEqual(t, ti.Return(), r)
+	scaled := *ti
+	scaled.SampleRate, scaled.SamplePos = 4*sampleRate, 400
+	assert.Equal(t, scaled, **(**vst2.TimeInfo)(unsafe.Pointer(&r)))
+	assert.Equal(t, float64(100), ti.SamplePos)
+}
@@ -0,0 +1,181 @@
+package vst2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Magic values of preset files.
+const (
+	presetChunkMagic  = "CcnK"
+	presetParamsMagic = "FxCk"
+	presetOpaqueMagic = "FPCh"
+	bankParamsMagic   = "FxBk"
+	bankOpaqueMagic   = "FBCh"
+	// bankReservedLen is a size of reserved bytes in bank header.
+	bankReservedLen = 128
+	// maxPresetCount limits number of params and programs, so corrupt
+	// files don't cause huge allocations.
+	maxPresetCount = 1 << 16
+)
+
+// Preset is a program (.fxp) or bank (.fxb) of plugin. Preset contains
+// either parameter values or opaque chunk.
+type Preset struct {
+	// PluginID is a unique identifier of plugin.
+	PluginID int32
+	// PluginVersion is a version of plugin that saved preset.
+	PluginVersion int32
+	// Bank is true if preset contains all programs of plugin.
+	Bank bool
+	// Name of the program. Empty for banks.
+	Name   string
+	Params []float32
+	Chunk  []byte
+	// Programs of bank saved as parameter values.
+	Programs []Preset
+	// CurrentProgram of bank.
+	CurrentProgram int
+}
+
+// presetHeader is a common header of preset files.
+type presetHeader struct {
+	ChunkMagic [4]byte
+	ByteSize   int32
+	FxMagic    [4]byte
+	Version    int32
+	FxID       int32
+	FxVersion  int32
+	// Count is a number of params or programs.
+	Count int32
+}
+
+// ReadPreset reads .fxp or .fxb file. Data is big-endian.
+func ReadPreset(r io.Reader) (Preset, error) {
+	var h presetHeader
+	if err := binary.Read(r, binary.BigEndian, &h); err != nil {
+		return Preset{}, fmt.Errorf("failed to read preset header: %w", err)
+	}
+	if string(h.ChunkMagic[:]) != presetChunkMagic {
+		return Preset{}, fmt.Errorf("invalid preset magic: %q", h.ChunkMagic)
+	}
+	p := Preset{
+		PluginID:      h.FxID,
+		PluginVersion: h.FxVersion,
+	}
+	switch magic := string(h.FxMagic[:]); magic {
+	case presetParamsMagic, presetOpaqueMagic:
+		var name [28]byte
+		if _, err := io.ReadFull(r, name[:]); err != nil {
+			return Preset{}, fmt.Errorf("failed to read program name: %w", err)
+		}
+		p.Name = goString(name[:])
+		if magic == presetParamsMagic {
+			return p, p.readParams(r, h.Count)
+		}
+		return p, p.readChunk(r)
+	case bankParamsMagic, bankOpaqueMagic:
+		p.Bank = true
+		var reserved [bankReservedLen]byte
+		if _, err := io.ReadFull(r, reserved[:]); err != nil {
+			return Preset{}, fmt.Errorf("failed to read bank header: %w", err)
+		}
+		// version 2 stores current program in reserved bytes.
+		if h.Version >= 2 {
+			p.CurrentProgram = int(int32(binary.BigEndian.Uint32(reserved[:4])))
+		}
+		if magic == bankOpaqueMagic {
+			return p, p.readChunk(r)
+		}
+		if h.Count < 0 || h.Count > maxPresetCount {
+			return Preset{}, fmt.Errorf("invalid number of programs: %d", h.Count)
+		}
+		p.Programs = make([]Preset, h.Count)
+		for i := range p.Programs {
+			prg, err := ReadPreset(r)
+			if err != nil {
+				return Preset{}, fmt.Errorf("failed to read program %d: %w", i, err)
+			}
+			if prg.Chunk != nil {
+				return Preset{}, fmt.Errorf("program %d of parameter bank contains chunk", i)
+			}
+			p.Programs[i] = prg
+		}
+		return p, nil
+	default:
+		return Preset{}, fmt.Errorf("unsupported preset type: %q", magic)
+	}
+}
+
+func (p *Preset) readParams(r io.Reader, count int32) error {
+	if count < 0 || count > maxPresetCount {
+		return fmt.Errorf("invalid number of params: %d", count)
+	}
+	p.Params = make([]float32, count)
+	if err := binary.Read(r, binary.BigEndian, p.Params); err != nil {
+		return fmt.Errorf("failed to read params: %w", err)
+	}
+	return nil
+}
+
+func (p *Preset) readChunk(r io.Reader) error {
+	var size int32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return fmt.Errorf("failed to read chunk size: %w", err)
+	}
+	if size < 0 {
+		return fmt.Errorf("invalid chunk size: %d", size)
+	}
+	var b bytes.Buffer
+	if n, err := io.CopyN(&b, r, int64(size)); err != nil {
+		return fmt.Errorf("failed to read chunk: %d of %d bytes: %w", n, size, err)
+	}
+	p.Chunk = b.Bytes()
+	return nil
+}
+
+// LoadPreset applies preset to plugin. Preset must be saved by the same
+// plugin.
+func (p *Plugin) LoadPreset(preset Preset) error {
+	if p.state == PluginClosed {
+		return ErrClosed
+	}
+	if preset.PluginID != p.UniqueID() {
+		return fmt.Errorf("preset for plugin %d can't be loaded to %s with id %d", preset.PluginID, p.Name, p.UniqueID())
+	}
+	if preset.Chunk != nil {
+		if err := p.SetChunk(preset.Chunk, !preset.Bank); err != nil {
+			return err
+		}
+		if !preset.Bank && preset.Name != "" {
+			p.SetProgramName(preset.Name)
+		}
+		return nil
+	}
+	if !preset.Bank {
+		return p.loadProgram(preset)
+	}
+	for i, prg := range preset.Programs {
+		p.SetProgram(i)
+		if err := p.loadProgram(prg); err != nil {
+			return fmt.Errorf("failed to load program %d: %w", i, err)
+		}
+	}
+	p.SetProgram(preset.CurrentProgram)
+	return nil
+}
+
+// loadProgram sets parameters and name of current program.
+func (p *Plugin) loadProgram(preset Preset) error {
+	for i, v := range preset.Params {
+		if err := p.SetParameter(i, v); err != nil {
+			return err
+		}
+	}
+	if preset.Name != "" {
+		p.SetProgramName(preset.Name)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package vst2_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeProgram writes .fxp program with parameter values.
+func writeProgram(b *bytes.Buffer, id int32, name string, params ...float32) {
+	b.WriteString("CcnK")
+	binary.Write(b, binary.BigEndian, int32(48+4*len(params)))
+	b.WriteString("FxCk")
+	binary.Write(b, binary.BigEndian, []int32{1, id, 1, int32(len(params))})
+	var prgName [28]byte
+	copy(prgName[:], name)
+	b.Write(prgName[:])
+	binary.Write(b, binary.BigEndian, params)
+}
+
+func TestReadPreset(t *testing.T) {
+	const id = 0x54657374
+	t.Run("program", func(t *testing.T) {
+		var b bytes.Buffer
+		writeProgram(&b, id, "test", 0.25, 1)
+		p, err := vst2.ReadPreset(&b)
+		require.Nil(t, err)
+		assert.Equal(t, int32(id), p.PluginID)
+		assert.False(t, p.Bank)
+		assert.Equal(t, "test", p.Name)
+		assert.Equal(t, []float32{0.25, 1}, p.Params)
+		assert.Nil(t, p.Chunk)
+	})
+	t.Run("bank chunk", func(t *testing.T) {
+		var b bytes.Buffer
+		b.WriteString("CcnK")
+		binary.Write(&b, binary.BigEndian, int32(160+3))
+		b.WriteString("FBCh")
+		binary.Write(&b, binary.BigEndian, []int32{2, id, 1, 4, 3})
+		b.Write(make([]byte, 124))
+		binary.Write(&b, binary.BigEndian, int32(3))
+		b.Write([]byte{1, 2, 3})
+		p, err := vst2.ReadPreset(&b)
+		require.Nil(t, err)
+		assert.True(t, p.Bank)
+		assert.Equal(t, 3, p.CurrentProgram)
+		assert.Equal(t, []byte{1, 2, 3}, p.Chunk)
+	})
+	t.Run("bank params", func(t *testing.T) {
+		var b bytes.Buffer
+		b.WriteString("CcnK")
+		binary.Write(&b, binary.BigEndian, int32(0))
+		b.WriteString("FxBk")
+		binary.Write(&b, binary.BigEndian, []int32{1, id, 1, 2})
+		b.Write(make([]byte, 128))
+		writeProgram(&b, id, "first", 0.1)
+		writeProgram(&b, id, "second", 0.2)
+		p, err := vst2.ReadPreset(&b)
+		require.Nil(t, err)
+		require.Equal(t, 2, len(p.Programs))
+		assert.Equal(t, "second", p.Programs[1].Name)
+		assert.Equal(t, []float32{0.2}, p.Programs[1].Params)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := vst2.ReadPreset(bytes.NewReader([]byte("RIFF0000WAVE")))
+		assert.NotNil(t, err)
+	})
+	t.Run("corrupt bank", func(t *testing.T) {
+		for _, count := range []int32{-1, 1 << 30} {
+			var b bytes.Buffer
+			b.WriteString("CcnK")
+			binary.Write(&b, binary.BigEndian, int32(0))
+			b.WriteString("FxBk")
+			binary.Write(&b, binary.BigEndian, []int32{1, id, 1, count})
+			b.Write(make([]byte, 128))
+			_, err := vst2.ReadPreset(&b)
+			assert.NotNil(t, err)
+		}
+	})
+	t.Run("corrupt params", func(t *testing.T) {
+		var b bytes.Buffer
+		writeProgram(&b, id, "test")
+		// overwrite number of params.
+		binary.BigEndian.PutUint32(b.Bytes()[24:], 1<<30)
+		_, err := vst2.ReadPreset(&b)
+		assert.NotNil(t, err)
+	})
+}
@@ -1,7 +1,9 @@
 package vst2
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"pipelined.dev/signal"
@@ -9,7 +11,7 @@ import (
 
 // Processor represents vst2 sound processor
 type Processor struct {
-	VST
+	*VST
 	plugin *Plugin
 
 	bufferSize  int
@@ -18,62 +20,369 @@ type Processor struct {
 
 	currentPosition int64
 
+	// MaxBlockSize is a max number of samples passed to plugin in one
+	// process call. It's set before plugin is resumed and never changes
+	// while processing. Larger buffers are split into blocks.
+	// DefaultMaxBlockSize is used if zero.
+	MaxBlockSize int
+
+	// Automation lanes applied during processing.
+	Automation []AutomationLane
+	// AutomationGranularity is a max number of samples processed with
+	// the same parameter values. Blocks are split if needed. If zero,
+	// automation is applied at block boundaries.
+	AutomationGranularity int
+	// Recorder records parameter changes made by plugin.
+	Recorder *AutomationRecorder
+	// ParameterQueueSize is a capacity of parameter queues.
+	// DefaultParameterQueueSize is used if zero.
+	ParameterQueueSize int
+	// params are changes queued by control code.
+	params *ParameterQueue
+	// automated are changes reported by plugin.
+	automated *ParameterQueue
+
+	// VariableIO enables processing with EffProcessVarIo call, so plugin
+	// can consume and produce different number of samples. It's used by
+	// time-stretching and resampling plugins.
+	VariableIO bool
+
+	// PanLaw is sent to plugin if PanLawGain is not zero.
+	PanLaw     PanLawType
+	PanLawGain float32
+	// TotalSamples is sent to plugin if not zero. It should be set for
+	// offline rendering.
+	TotalSamples int
+	// Chunk is set to plugin before it's resumed. It's used to restore
+	// saved state of plugin.
+	Chunk []byte
+	// Clock provides time that is reported to plugin in TimeInfo.
+	// WallClock is used if nil. SampleClock or FixedClock make renders
+	// reproducible.
+	Clock Clock
+
+	state ProcessorState
+
 	// references are needed to free them in Flush.
 	doubleIn  DoubleBuffer
 	doubleOut DoubleBuffer
+	varIO     *variableIO
+}
+
+const (
+	// DefaultParameterQueueSize is used if Processor.ParameterQueueSize is not set.
+	DefaultParameterQueueSize = 256
+	// DefaultMaxBlockSize is used if Processor.MaxBlockSize is not set.
+	DefaultMaxBlockSize = 512
+)
+
+// ProcessorState is a lifecycle state of Processor.
+type ProcessorState int
+
+const (
+	// ProcessorClosed means that plugin isn't loaded.
+	ProcessorClosed ProcessorState = iota
+	// ProcessorOpened means that plugin is loaded and suspended.
+	ProcessorOpened
+	// ProcessorResumed means that plugin is resumed.
+	ProcessorResumed
+	// ProcessorProcessing means that plugin is notified about process calls.
+	ProcessorProcessing
+)
+
+var processorStates = [...]string{
+	ProcessorClosed:     "closed",
+	ProcessorOpened:     "opened",
+	ProcessorResumed:    "resumed",
+	ProcessorProcessing: "processing",
+}
+
+func (s ProcessorState) String() string {
+	if s < 0 || int(s) >= len(processorStates) {
+		return fmt.Sprintf("ProcessorState(%d)", s)
+	}
+	return processorStates[s]
+}
+
+// ErrProcessorState is returned when processor calls are out of order.
+var ErrProcessorState = errors.New("invalid processor state")
+
+// State returns current lifecycle state of processor.
+func (p *Processor) State() ProcessorState {
+	return p.state
+}
+
+// Plugin returns loaded plugin. It's nil before Process call and after
+// Flush.
+func (p *Processor) Plugin() *Plugin {
+	return p.plugin
+}
+
+// Latency returns delay of processor output in samples. It includes
+// initial delay of plugin and silence that variable io processing sends
+// before plugin produced enough samples. The latter is known once the
+// first produced samples are sent.
+func (p *Processor) Latency() int {
+	if p.plugin == nil {
+		return 0
+	}
+	latency := p.plugin.InitialDelay()
+	if p.varIO != nil {
+		latency += p.varIO.latency
+	}
+	return latency
 }
 
-// Process returns processor function with default settings initialized.
+// Process loads the plugin, resumes it and returns processor function.
+// Plugin is notified with EffStartProcess before the first block.
+// Pipeline buffers are Go memory that plugin can't access, so every block
+// is copied to C buffer and back. Chain processes DoubleBuffer without
+// these copies.
 func (p *Processor) Process(pipeID string, sampleRate signal.SampleRate, numChannels int) (func(signal.Float64) error, error) {
 	p.sampleRate = sampleRate
 	p.numChannels = numChannels
-	p.plugin = p.VST.Load(p.callback())
-
-	p.plugin.SetSampleRate(int(p.sampleRate))
-	p.plugin.SetSpeakerArrangement(newSpeakerArrangement(p.numChannels), newSpeakerArrangement(p.numChannels))
-	p.plugin.Start()
-	var size int
-	var out signal.Float64
+	if err := p.open(); err != nil {
+		return nil, err
+	}
+	if err := p.resume(); err != nil {
+		p.close()
+		return nil, err
+	}
+	ti := p.plugin.TimeInfo()
+	if p.VariableIO {
+		p.varIO = newVariableIO(numChannels, p.bufferSize)
+		return func(in signal.Float64) error {
+			if err := p.startProcess(); err != nil {
+				return err
+			}
+			// blocks are split like in regular processing.
+			for off := 0; off < in.Size(); off += p.bufferSize {
+				n := min(p.bufferSize, in.Size()-off)
+				p.applyParameters()
+				p.automate()
+				ti.SamplePos = float64(p.currentPosition)
+				if err := p.varIO.process(p.plugin, p.varIO.slice(in, off, n)); err != nil {
+					return fmt.Errorf("failed to process %s: %w", p.plugin.Name, err)
+				}
+				atomic.AddInt64(&p.currentPosition, int64(n))
+			}
+			return nil
+		}, nil
+	}
 	return func(in signal.Float64) error {
-		// new buffer size.
-		if size != in.Size() {
-			size = in.Size()
-			p.plugin.SetBufferSize(size)
-
-			// reset buffers.
-			p.doubleIn.Free()
-			p.doubleOut.Free()
-			p.doubleIn = NewDoubleBuffer(numChannels, size)
-			p.doubleOut = NewDoubleBuffer(numChannels, size)
-			out = signal.Float64Buffer(numChannels, size)
+		// automation is applied at the start of every block.
+		size := p.bufferSize
+		if p.AutomationGranularity > 0 && p.AutomationGranularity < size {
+			size = p.AutomationGranularity
 		}
-		p.doubleIn.CopyFrom(in)
-		p.plugin.ProcessDouble(p.doubleIn, p.doubleOut)
-		p.currentPosition += int64(in.Size())
-		p.doubleOut.CopyTo(out)
-
-		// copy result back to input buffer.
-		for i := range out {
-			copy(in[i], out[i])
+		if err := p.startProcess(); err != nil {
+			return err
+		}
+		for off := 0; off < in.Size(); off += size {
+			n := min(size, in.Size()-off)
+			p.applyParameters()
+			p.automate()
+			// plugin can't access Go memory, so input is copied to C
+			// buffer and output is copied right into pipeline buffer.
+			din, dout := p.doubleIn.slice(n), p.doubleOut.slice(n)
+			for i := 0; i < numChannels; i++ {
+				copy(din.Channel(i), in[i][off:off+n])
+			}
+			ti.SamplePos = float64(p.currentPosition)
+			if err := p.plugin.ProcessDouble(din, dout); err != nil {
+				return fmt.Errorf("failed to process %s: %w", p.plugin.Name, err)
+			}
+			atomic.AddInt64(&p.currentPosition, int64(n))
+			for i := 0; i < numChannels; i++ {
+				copy(in[i][off:off+n], dout.Channel(i))
+			}
 		}
 		return nil
 	}, nil
 }
 
-// Flush suspends plugin.
+// Flush stops processing, suspends and closes plugin.
 func (p *Processor) Flush(string) error {
-	p.plugin.Stop()
+	if p.state == ProcessorClosed {
+		return fmt.Errorf("%w: flush when %v", ErrProcessorState, p.state)
+	}
+	if p.state == ProcessorProcessing {
+		if err := p.stopProcess(); err != nil {
+			return err
+		}
+	}
+	// plugin is not resumed if resume failed.
+	if p.state == ProcessorResumed {
+		if err := p.suspend(); err != nil {
+			return err
+		}
+	}
+	return p.close()
+}
+
+// open loads plugin and configures it.
+func (p *Processor) open() error {
+	if p.state != ProcessorClosed {
+		return fmt.Errorf("%w: open when %v", ErrProcessorState, p.state)
+	}
+	queueSize := p.ParameterQueueSize
+	if queueSize == 0 {
+		queueSize = DefaultParameterQueueSize
+	}
+	p.params = NewParameterQueue(queueSize)
+	p.automated = NewParameterQueue(queueSize)
+	p.plugin = p.VST.Load(p.callback())
+	if p.plugin == nil {
+		return fmt.Errorf("failed to load plugin %s", p.VST.Name)
+	}
+	if err := p.configure(); err != nil {
+		p.release()
+		return err
+	}
+	p.state = ProcessorOpened
+	return nil
+}
+
+// configure sets up loaded plugin and allocates buffers.
+func (p *Processor) configure() error {
+	p.currentPosition = 0
+	p.bufferSize = p.MaxBlockSize
+	if p.bufferSize == 0 {
+		p.bufferSize = DefaultMaxBlockSize
+	}
+
+	if err := p.plugin.SetSampleRate(int(p.sampleRate)); err != nil {
+		return fmt.Errorf("failed to set sample rate: %w", err)
+	}
+	arrangement := DefaultSpeakerArrangement(p.numChannels)
+	if arrangement == nil {
+		return fmt.Errorf("unsupported number of channels: %d", p.numChannels)
+	}
+	// plugins without arrangements support reject them, such plugins
+	// use their default arrangement.
+	if err := p.plugin.SetSpeakerArrangement(arrangement, arrangement); err != nil && !errors.Is(err, ErrArrangementRejected) {
+		return fmt.Errorf("failed to set speaker arrangement: %w", err)
+	}
+	if err := p.plugin.SetBufferSize(p.bufferSize); err != nil {
+		return fmt.Errorf("failed to set buffer size: %w", err)
+	}
+	p.doubleIn = NewDoubleBuffer(p.numChannels, p.bufferSize)
+	p.doubleOut = NewDoubleBuffer(p.numChannels, p.bufferSize)
+	if p.PanLawGain != 0 {
+		p.plugin.SetPanLaw(p.PanLaw, p.PanLawGain)
+	}
+	if p.TotalSamples > 0 {
+		p.plugin.SetTotalSamplesToProcess(p.TotalSamples)
+	}
+	if p.Chunk != nil {
+		if err := p.plugin.SetChunk(p.Chunk, false); err != nil {
+			return fmt.Errorf("failed to set chunk: %w", err)
+		}
+	}
+	// time info is updated in place before every block.
+	*p.plugin.TimeInfo() = TimeInfo{
+		SampleRate:         float64(p.sampleRate),
+		TimeSigNumerator:   4,
+		TimeSigDenominator: 4,
+		Flags:              NanosValid | TimeSigValid,
+	}
+	return nil
+}
+
+// resume plugin.
+func (p *Processor) resume() error {
+	if p.state != ProcessorOpened {
+		return fmt.Errorf("%w: resume when %v", ErrProcessorState, p.state)
+	}
+	if err := p.plugin.Start(); err != nil {
+		return fmt.Errorf("failed to resume %s: %w", p.plugin.Name, err)
+	}
+	p.state = ProcessorResumed
+	return nil
+}
+
+// startProcess notifies plugin that process calls will follow. It's
+// no-op if plugin is already notified.
+func (p *Processor) startProcess() error {
+	switch p.state {
+	case ProcessorProcessing:
+		return nil
+	case ProcessorResumed:
+		if err := p.plugin.StartProcess(); err != nil {
+			return fmt.Errorf("failed to start process %s: %w", p.plugin.Name, err)
+		}
+		p.state = ProcessorProcessing
+		return nil
+	default:
+		return fmt.Errorf("%w: process when %v", ErrProcessorState, p.state)
+	}
+}
+
+// stopProcess notifies plugin that process calls are stopped.
+func (p *Processor) stopProcess() error {
+	if p.state != ProcessorProcessing {
+		return fmt.Errorf("%w: stop process when %v", ErrProcessorState, p.state)
+	}
+	if err := p.plugin.StopProcess(); err != nil {
+		return fmt.Errorf("failed to stop process %s: %w", p.plugin.Name, err)
+	}
+	p.state = ProcessorResumed
+	return nil
+}
+
+// suspend plugin.
+func (p *Processor) suspend() error {
+	if p.state != ProcessorResumed {
+		return fmt.Errorf("%w: suspend when %v", ErrProcessorState, p.state)
+	}
+	if err := p.plugin.Stop(); err != nil {
+		return fmt.Errorf("failed to suspend %s: %w", p.plugin.Name, err)
+	}
+	p.state = ProcessorOpened
+	return nil
+}
+
+// close plugin and free buffers.
+func (p *Processor) close() error {
+	if p.state != ProcessorOpened {
+		return fmt.Errorf("%w: close when %v", ErrProcessorState, p.state)
+	}
+	p.release()
+	p.state = ProcessorClosed
+	return nil
+}
+
+// release closes plugin and frees buffers.
+func (p *Processor) release() {
 	p.doubleIn.Free()
 	p.doubleOut.Free()
-	return nil
+	p.doubleIn, p.doubleOut = DoubleBuffer{}, DoubleBuffer{}
+	if p.varIO != nil {
+		p.varIO.free()
+		p.varIO = nil
+	}
+	p.plugin.Close()
+	p.plugin = nil
+}
+
+// now returns current time of processor clock.
+func (p *Processor) now() time.Time {
+	position := atomic.LoadInt64(&p.currentPosition)
+	if p.Clock != nil {
+		return p.Clock.Now(position, p.sampleRate)
+	}
+	return WallClock{}.Now(position, p.sampleRate)
 }
 
 // wraped callback with session.
 func (p *Processor) callback() HostCallbackFunc {
 	return func(opcode HostOpcode, index Index, value Value, ptr Ptr, opt Opt) Return {
-		fmt.Printf("Callback: %v\n", opcode)
 		switch opcode {
 		case HostIdle:
+			// plugin can call back during open, before it's assigned.
+			if p.plugin == nil {
+				return 0
+			}
 			p.plugin.Dispatch(EffEditIdle, 0, 0, nil, 0)
 		case HostGetCurrentProcessLevel:
 			return Return(ProcessLevelRealtime)
@@ -82,15 +391,31 @@ func (p *Processor) callback() HostCallbackFunc {
 		case HostGetBlockSize:
 			return Return(p.bufferSize)
 		case HostGetTime:
-			nanoseconds := time.Now().UnixNano()
-			ti := &TimeInfo{
-				SampleRate:         float64(p.sampleRate),
-				SamplePos:          float64(p.currentPosition),
-				NanoSeconds:        float64(nanoseconds),
-				TimeSigNumerator:   4,
-				TimeSigDenominator: 4,
+			if p.plugin == nil {
+				return 0
 			}
+			ti := p.plugin.TimeInfo()
+			ti.NanoSeconds = float64(p.now().UnixNano())
 			return ti.Return()
+		case HostAutomate:
+			p.automated.Push(ParameterChange{Index: int(index), Value: float32(opt)})
+			if p.Recorder != nil {
+				p.Recorder.Automate(int(index), float32(opt), atomic.LoadInt64(&p.currentPosition))
+			}
+		case HostBeginEdit:
+			if p.Recorder != nil {
+				p.Recorder.BeginEdit(int(index), atomic.LoadInt64(&p.currentPosition))
+				return 1
+			}
+		case HostEndEdit:
+			if p.Recorder != nil {
+				p.Recorder.EndEdit(int(index), atomic.LoadInt64(&p.currentPosition))
+				return 1
+			}
+		case HostGetAutomationState:
+			if p.Recorder != nil {
+				return Return(p.Recorder.State())
+			}
 		default:
 			// log.Printf("Plugin requested value of opcode %v\n", opcode)
 			break
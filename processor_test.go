@@ -0,0 +1,84 @@
+package vst2_test
+
+import (
+	"errors"
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessorState(t *testing.T) {
+	var p vst2.Processor
+	assert.Equal(t, vst2.ProcessorClosed, p.State())
+	err := p.Flush("")
+	assert.True(t, errors.Is(err, vst2.ErrProcessorState))
+	err = p.SetParameter(0, 1)
+	assert.True(t, errors.Is(err, vst2.ErrProcessorState))
+	_, ok := p.AutomatedParameter()
+	assert.False(t, ok)
+	assert.Equal(t, "closed", vst2.ProcessorClosed.String())
+	assert.Equal(t, "ProcessorState(10)", vst2.ProcessorState(10).String())
+}
+
+func TestProcessorChannels(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	p := vst2.Processor{VST: v}
+	_, err = p.Process("", sampleRate, -1)
+	assert.NotNil(t, err)
+	assert.Equal(t, vst2.ProcessorClosed, p.State())
+	assert.Nil(t, p.Plugin())
+}
+
+func TestProcessorBlocks(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	const maxBlockSize, size = 16, 5*16 + 3
+	s := signal.Float64Buffer(2, size)
+	for i := range s[0] {
+		s[0][i], s[1][i] = float64(i)/size, -float64(i)/size
+	}
+
+	// reference is processed by plugin block by block.
+	ref := loadResumed(t, v, maxBlockSize)
+	defer ref.Close()
+	require.Nil(t, ref.SetParameter(0, 0.25))
+	expected := signal.Float64Buffer(2, size)
+	for off := 0; off < size; off += maxBlockSize {
+		n := min(maxBlockSize, size-off)
+		in, out := vst2.NewDoubleBuffer(2, n), vst2.NewDoubleBuffer(2, n)
+		in.CopyFrom(signal.Float64{s[0][off : off+n], s[1][off : off+n]})
+		require.Nil(t, ref.ProcessDouble(in, out))
+		out.CopyTo(signal.Float64{expected[0][off : off+n], expected[1][off : off+n]})
+		in.Free()
+		out.Free()
+	}
+
+	p := vst2.Processor{VST: v, MaxBlockSize: maxBlockSize}
+	fn, err := p.Process("", sampleRate, 2)
+	require.Nil(t, err)
+	defer p.Flush("")
+	require.Nil(t, p.SetParameter(0, 0.25))
+	in, out := vst2.ProcessorBuffers(&p)
+	require.Nil(t, fn(s))
+	assert.Equal(t, expected, s)
+	// buffers larger than max block size don't cause reallocation.
+	processedIn, processedOut := vst2.ProcessorBuffers(&p)
+	assert.Equal(t, in, processedIn)
+	assert.Equal(t, out, processedOut)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,121 @@
+package vst2
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrParameterQueueFull is returned when parameter change can't be
+// queued.
+var ErrParameterQueueFull = errors.New("parameter queue is full")
+
+// ParameterChange is a new value of plugin parameter.
+type ParameterChange struct {
+	Index int
+	Value float32
+}
+
+// ParameterQueue is a lock-free queue of parameter changes. It's safe to
+// use with multiple producer goroutines and a single consumer goroutine.
+// Plugins report automation from both GUI and audio threads, so there can
+// be more than one producer.
+type ParameterQueue struct {
+	// head is a read position, only consumer updates it.
+	head uint64
+	// tail is a write position, producers reserve slots by advancing it.
+	tail uint64
+	mask uint64
+	buf  []parameterSlot
+}
+
+// parameterSlot is an element of queue. Sequence tells if slot is ready
+// to be written or read in the current lap of the ring.
+type parameterSlot struct {
+	seq    uint64
+	change ParameterChange
+}
+
+// NewParameterQueue returns queue with capacity rounded up to the power
+// of two.
+func NewParameterQueue(capacity int) *ParameterQueue {
+	size := 1
+	for size < capacity {
+		size <<= 1
+	}
+	q := ParameterQueue{
+		mask: uint64(size - 1),
+		buf:  make([]parameterSlot, size),
+	}
+	for i := range q.buf {
+		q.buf[i].seq = uint64(i)
+	}
+	return &q
+}
+
+// Push adds change to the queue. False is returned if queue is full.
+func (q *ParameterQueue) Push(c ParameterChange) bool {
+	for {
+		tail := atomic.LoadUint64(&q.tail)
+		slot := &q.buf[tail&q.mask]
+		switch seq := atomic.LoadUint64(&slot.seq); {
+		case seq == tail:
+			// slot is free, try to reserve it.
+			if !atomic.CompareAndSwapUint64(&q.tail, tail, tail+1) {
+				continue
+			}
+			slot.change = c
+			atomic.StoreUint64(&slot.seq, tail+1)
+			return true
+		case seq < tail:
+			// slot wasn't read in the previous lap.
+			return false
+		}
+		// another producer reserved the slot.
+	}
+}
+
+// Pop removes the oldest change from the queue. False is returned if
+// queue is empty.
+func (q *ParameterQueue) Pop() (ParameterChange, bool) {
+	head := atomic.LoadUint64(&q.head)
+	slot := &q.buf[head&q.mask]
+	if atomic.LoadUint64(&slot.seq) != head+1 {
+		return ParameterChange{}, false
+	}
+	c := slot.change
+	// free the slot for the next lap.
+	atomic.StoreUint64(&slot.seq, head+uint64(len(q.buf)))
+	atomic.StoreUint64(&q.head, head+1)
+	return c, true
+}
+
+// SetParameter queues new parameter value, it's applied to plugin right
+// before the next block is processed. It must be called after Process.
+// ErrProcessorState is returned if queues are not allocated yet.
+func (p *Processor) SetParameter(index int, value float32) error {
+	if p.params == nil {
+		return fmt.Errorf("%w: set parameter when %v", ErrProcessorState, p.state)
+	}
+	if !p.params.Push(ParameterChange{Index: index, Value: value}) {
+		return fmt.Errorf("failed to set parameter %d: %w", index, ErrParameterQueueFull)
+	}
+	return nil
+}
+
+// AutomatedParameter returns the next parameter value reported by
+// plugin with HostAutomate call. It must be called from a single
+// goroutine after Process. False is returned if there are no changes.
+func (p *Processor) AutomatedParameter() (ParameterChange, bool) {
+	if p.automated == nil {
+		return ParameterChange{}, false
+	}
+	return p.automated.Pop()
+}
+
+// applyParameters drains queued parameter changes into plugin.
+func (p *Processor) applyParameters() {
+	for c, ok := p.params.Pop(); ok; c, ok = p.params.Pop() {
+		p.plugin.SetParameter(c.Index, c.Value)
+	}
+}
@@ -0,0 +1,82 @@
+package vst2_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameterQueue(t *testing.T) {
+	q := vst2.NewParameterQueue(3)
+	for i := 0; i < 4; i++ {
+		assert.True(t, q.Push(vst2.ParameterChange{Index: i}))
+	}
+	assert.False(t, q.Push(vst2.ParameterChange{Index: 4}))
+
+	for i := 0; i < 4; i++ {
+		c, ok := q.Pop()
+		assert.True(t, ok)
+		assert.Equal(t, i, c.Index)
+	}
+	_, ok := q.Pop()
+	assert.False(t, ok)
+}
+
+func TestParameterQueueConcurrent(t *testing.T) {
+	const n = 1000
+	q := vst2.NewParameterQueue(16)
+	go func() {
+		for i := 0; i < n; i++ {
+			for !q.Push(vst2.ParameterChange{Index: i}) {
+				runtime.Gosched()
+			}
+		}
+	}()
+	for i := 0; i < n; i++ {
+		c, ok := q.Pop()
+		for !ok {
+			runtime.Gosched()
+			c, ok = q.Pop()
+		}
+		if c.Index != i {
+			t.Fatalf("unexpected change order: expected %v got %v", i, c.Index)
+		}
+	}
+}
+
+func TestParameterQueueProducers(t *testing.T) {
+	const producers, n = 4, 1000
+	q := vst2.NewParameterQueue(16)
+	var wg sync.WaitGroup
+	wg.Add(producers)
+	for p := 0; p < producers; p++ {
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				for !q.Push(vst2.ParameterChange{Index: p, Value: float32(i)}) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+	// changes of every producer must be received in order.
+	next := make([]float32, producers)
+	for received := 0; received < producers*n; received++ {
+		c, ok := q.Pop()
+		for !ok {
+			runtime.Gosched()
+			c, ok = q.Pop()
+		}
+		if c.Value != next[c.Index] {
+			t.Fatalf("unexpected change order of producer %d: expected %v got %v", c.Index, next[c.Index], c.Value)
+		}
+		next[c.Index]++
+	}
+	wg.Wait()
+	_, ok := q.Pop()
+	assert.False(t, ok)
+}
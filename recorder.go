@@ -0,0 +1,223 @@
+package vst2
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AutomationMode defines which parameter changes are recorded.
+type AutomationMode int
+
+const (
+	// AutomationOff doesn't read nor record automation.
+	AutomationOff AutomationMode = iota
+	// AutomationRead only reads automation, nothing is recorded.
+	AutomationRead
+	// AutomationWrite records all parameter changes.
+	AutomationWrite
+	// AutomationTouch records parameter changes only while parameter is
+	// being edited.
+	AutomationTouch
+	// AutomationLatch records parameter changes from the first edit of
+	// the parameter until recorder is reset.
+	AutomationLatch
+)
+
+// AutomationEventKind defines the kind of recorded event.
+type AutomationEventKind int
+
+const (
+	// AutomationValue is a parameter value change.
+	AutomationValue AutomationEventKind = iota
+	// AutomationBeginEdit is a start of parameter edit gesture.
+	AutomationBeginEdit
+	// AutomationEndEdit is an end of parameter edit gesture.
+	AutomationEndEdit
+)
+
+var automationEventKinds = [...]string{
+	AutomationValue:     "value",
+	AutomationBeginEdit: "begin",
+	AutomationEndEdit:   "end",
+}
+
+func (k AutomationEventKind) String() string {
+	if k < 0 || int(k) >= len(automationEventKinds) {
+		return fmt.Sprintf("AutomationEventKind(%d)", k)
+	}
+	return automationEventKinds[k]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k AutomationEventKind) MarshalText() ([]byte, error) {
+	if k < 0 || int(k) >= len(automationEventKinds) {
+		return nil, fmt.Errorf("unknown automation event kind: %d", k)
+	}
+	return []byte(automationEventKinds[k]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *AutomationEventKind) UnmarshalText(text []byte) error {
+	for i, s := range automationEventKinds {
+		if s == string(text) {
+			*k = AutomationEventKind(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown automation event kind: %s", text)
+}
+
+// AutomationEvent is a recorded parameter change or edit gesture boundary.
+type AutomationEvent struct {
+	Kind  AutomationEventKind `json:"kind"`
+	Index int                 `json:"index"`
+	Value float32             `json:"value"`
+	Pos   int64               `json:"pos"`
+}
+
+// AutomationRecorder records parameter changes reported by plugin with
+// HostAutomate, HostBeginEdit and HostEndEdit calls. It's safe for
+// concurrent use.
+type AutomationRecorder struct {
+	mutex   sync.Mutex
+	mode    AutomationMode
+	editing map[int]bool
+	latched map[int]bool
+	events  []AutomationEvent
+}
+
+// NewAutomationRecorder returns recorder in provided mode.
+func NewAutomationRecorder(mode AutomationMode) *AutomationRecorder {
+	return &AutomationRecorder{
+		mode:    mode,
+		editing: make(map[int]bool),
+		latched: make(map[int]bool),
+	}
+}
+
+// SetMode changes recording mode. Latched parameters are released.
+func (r *AutomationRecorder) SetMode(mode AutomationMode) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.mode = mode
+	r.latched = make(map[int]bool)
+}
+
+// State returns automation state that corresponds to recording mode.
+func (r *AutomationRecorder) State() AutomationState {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	switch r.mode {
+	case AutomationOff:
+		return AutomationStateOff
+	case AutomationRead:
+		return AutomationStateRead
+	case AutomationWrite:
+		return AutomationStateWrite
+	case AutomationTouch, AutomationLatch:
+		return AutomationStateReadWrite
+	default:
+		return AutomationStateUnsupported
+	}
+}
+
+// Automate records parameter value at sample position.
+func (r *AutomationRecorder) Automate(index int, value float32, pos int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	switch r.mode {
+	case AutomationWrite:
+	case AutomationTouch:
+		if !r.editing[index] {
+			return
+		}
+	case AutomationLatch:
+		if !r.latched[index] {
+			return
+		}
+	default:
+		return
+	}
+	r.events = append(r.events, AutomationEvent{
+		Kind:  AutomationValue,
+		Index: index,
+		Value: value,
+		Pos:   pos,
+	})
+}
+
+// BeginEdit records the start of parameter edit gesture.
+func (r *AutomationRecorder) BeginEdit(index int, pos int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.editing[index] = true
+	if r.mode == AutomationLatch {
+		r.latched[index] = true
+	}
+	r.gesture(AutomationBeginEdit, index, pos)
+}
+
+// EndEdit records the end of parameter edit gesture.
+func (r *AutomationRecorder) EndEdit(index int, pos int64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.editing[index] {
+		return
+	}
+	delete(r.editing, index)
+	r.gesture(AutomationEndEdit, index, pos)
+}
+
+// gesture records gesture boundary if current mode writes automation.
+func (r *AutomationRecorder) gesture(kind AutomationEventKind, index int, pos int64) {
+	switch r.mode {
+	case AutomationWrite, AutomationTouch, AutomationLatch:
+		r.events = append(r.events, AutomationEvent{
+			Kind:  kind,
+			Index: index,
+			Pos:   pos,
+		})
+	}
+}
+
+// Events returns a copy of recorded events.
+func (r *AutomationRecorder) Events() []AutomationEvent {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]AutomationEvent(nil), r.events...)
+}
+
+// Lanes converts recorded values into automation lanes, one per
+// parameter, in order of the first change.
+func (r *AutomationRecorder) Lanes() []AutomationLane {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var lanes []AutomationLane
+	indexes := make(map[int]int)
+	for _, e := range r.events {
+		if e.Kind != AutomationValue {
+			continue
+		}
+		i, ok := indexes[e.Index]
+		if !ok {
+			i = len(lanes)
+			indexes[e.Index] = i
+			lanes = append(lanes, AutomationLane{Index: e.Index})
+		}
+		lanes[i].Points = append(lanes[i].Points, Breakpoint{
+			Pos:   e.Pos,
+			Value: e.Value,
+			Curve: CurveStep,
+		})
+	}
+	return lanes
+}
+
+// Reset removes recorded events and releases latched parameters.
+func (r *AutomationRecorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.events = nil
+	r.editing = make(map[int]bool)
+	r.latched = make(map[int]bool)
+}
@@ -0,0 +1,188 @@
+package vst2
+
+// #include <stdlib.h>
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+const (
+	// minSpeakers is a number of speakers in SDK structure, plugins
+	// might expect at least this number of speakers allocated.
+	minSpeakers = 8
+	// maxSpeakers is used to cast C memory to Go slice.
+	maxSpeakers = 1 << 16
+)
+
+var (
+	// speakerLayouts contains speaker types of predefined arrangements.
+	speakerLayouts = map[SpeakerArrangementType][]SpeakerType{
+		SpeakerArrEmpty:          {},
+		SpeakerArrMono:           {SpeakerM},
+		SpeakerArrStereo:         {SpeakerL, SpeakerR},
+		SpeakerArrStereoSurround: {SpeakerLs, SpeakerRs},
+		SpeakerArrStereoCenter:   {SpeakerLc, SpeakerRc},
+		SpeakerArrStereoSide:     {SpeakerSl, SpeakerSr},
+		SpeakerArrStereoCLfe:     {SpeakerC, SpeakerLfe},
+		SpeakerArr30Cine:         {SpeakerL, SpeakerR, SpeakerC},
+		SpeakerArr30Music:        {SpeakerL, SpeakerR, SpeakerS},
+		SpeakerArr31Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe},
+		SpeakerArr31Music:        {SpeakerL, SpeakerR, SpeakerLfe, SpeakerS},
+		SpeakerArr40Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerS},
+		SpeakerArr40Music:        {SpeakerL, SpeakerR, SpeakerLs, SpeakerRs},
+		SpeakerArr41Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe, SpeakerS},
+		SpeakerArr41Music:        {SpeakerL, SpeakerR, SpeakerLfe, SpeakerLs, SpeakerRs},
+		SpeakerArr50:             {SpeakerL, SpeakerR, SpeakerC, SpeakerLs, SpeakerRs},
+		SpeakerArr51:             {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe, SpeakerLs, SpeakerRs},
+		SpeakerArr60Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerLs, SpeakerRs, SpeakerCs},
+		SpeakerArr60Music:        {SpeakerL, SpeakerR, SpeakerLs, SpeakerRs, SpeakerSl, SpeakerSr},
+		SpeakerArr61Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe, SpeakerLs, SpeakerRs, SpeakerCs},
+		SpeakerArr61Music:        {SpeakerL, SpeakerR, SpeakerLfe, SpeakerLs, SpeakerRs, SpeakerSl, SpeakerSr},
+		SpeakerArr70Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerLs, SpeakerRs, SpeakerLc, SpeakerRc},
+		SpeakerArr70Music:        {SpeakerL, SpeakerR, SpeakerC, SpeakerLs, SpeakerRs, SpeakerSl, SpeakerSr},
+		SpeakerArr71Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe, SpeakerLs, SpeakerRs, SpeakerLc, SpeakerRc},
+		SpeakerArr71Music:        {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe, SpeakerLs, SpeakerRs, SpeakerSl, SpeakerSr},
+		SpeakerArr80Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerLs, SpeakerRs, SpeakerLc, SpeakerRc, SpeakerCs},
+		SpeakerArr80Music:        {SpeakerL, SpeakerR, SpeakerC, SpeakerLs, SpeakerRs, SpeakerCs, SpeakerSl, SpeakerSr},
+		SpeakerArr81Cine:         {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe, SpeakerLs, SpeakerRs, SpeakerLc, SpeakerRc, SpeakerCs},
+		SpeakerArr81Music:        {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe, SpeakerLs, SpeakerRs, SpeakerCs, SpeakerSl, SpeakerSr},
+		SpeakerArr102:            {SpeakerL, SpeakerR, SpeakerC, SpeakerLfe, SpeakerLs, SpeakerRs, SpeakerTfl, SpeakerTfc, SpeakerTfr, SpeakerTrl, SpeakerTrr, SpeakerLfe2},
+	}
+
+	// defaultArrangements are used for channel count.
+	defaultArrangements = []SpeakerArrangementType{
+		SpeakerArrEmpty,
+		SpeakerArrMono,
+		SpeakerArrStereo,
+		SpeakerArr30Cine,
+		SpeakerArr40Music,
+		SpeakerArr50,
+		SpeakerArr51,
+		SpeakerArr70Music,
+		SpeakerArr71Music,
+	}
+
+	speakerNames = [...]string{
+		SpeakerM:    "M",
+		SpeakerL:    "L",
+		SpeakerR:    "R",
+		SpeakerC:    "C",
+		SpeakerLfe:  "Lfe",
+		SpeakerLs:   "Ls",
+		SpeakerRs:   "Rs",
+		SpeakerLc:   "Lc",
+		SpeakerRc:   "Rc",
+		SpeakerS:    "S",
+		SpeakerSl:   "Sl",
+		SpeakerSr:   "Sr",
+		SpeakerTm:   "Tm",
+		SpeakerTfl:  "Tfl",
+		SpeakerTfc:  "Tfc",
+		SpeakerTfr:  "Tfr",
+		SpeakerTrl:  "Trl",
+		SpeakerTrc:  "Trc",
+		SpeakerTrr:  "Trr",
+		SpeakerLfe2: "Lfe2",
+	}
+)
+
+func (t SpeakerType) String() string {
+	if t >= 0 && int(t) < len(speakerNames) {
+		return speakerNames[t]
+	}
+	if t == SpeakerUndefined {
+		return "Undefined"
+	}
+	return fmt.Sprintf("SpeakerType(%d)", t)
+}
+
+// NewSpeakerArrangement returns arrangement of provided type with
+// speaker types and names populated. Nil is returned for user-defined
+// and unknown types.
+func NewSpeakerArrangement(t SpeakerArrangementType) *SpeakerArrangement {
+	layout, ok := speakerLayouts[t]
+	if !ok {
+		return nil
+	}
+	sa := SpeakerArrangement{
+		Type:     t,
+		Speakers: make([]Speaker, len(layout)),
+	}
+	for i, st := range layout {
+		sa.Speakers[i].Type = st
+		copy(sa.Speakers[i].Name[:len(sa.Speakers[i].Name)-1], st.String())
+	}
+	return &sa
+}
+
+// DefaultSpeakerArrangement returns default arrangement for number of
+// channels. User-defined arrangement with undefined speakers is
+// returned if there is no default one. Nil is returned if number of
+// channels is negative or too large.
+func DefaultSpeakerArrangement(numChannels int) *SpeakerArrangement {
+	if numChannels < 0 || numChannels > maxSpeakers {
+		return nil
+	}
+	if numChannels < len(defaultArrangements) {
+		return NewSpeakerArrangement(defaultArrangements[numChannels])
+	}
+	sa := SpeakerArrangement{
+		Type:     SpeakerArrUserDefined,
+		Speakers: make([]Speaker, numChannels),
+	}
+	for i := range sa.Speakers {
+		sa.Speakers[i].Type = SpeakerUndefined
+	}
+	return &sa
+}
+
+// NumChannels returns number of channels in arrangement.
+func (sa *SpeakerArrangement) NumChannels() int {
+	if sa == nil {
+		return 0
+	}
+	return len(sa.Speakers)
+}
+
+// alloc copies arrangement into C memory. Returned value must be freed.
+func (sa *SpeakerArrangement) alloc() *speakerArrangement {
+	if sa == nil {
+		return nil
+	}
+	numSpeakers := len(sa.Speakers)
+	if numSpeakers < minSpeakers {
+		numSpeakers = minSpeakers
+	}
+	size := unsafe.Sizeof(speakerArrangement{}) + uintptr(numSpeakers)*unsafe.Sizeof(Speaker{})
+	csa := (*speakerArrangement)(C.calloc(1, C.size_t(size)))
+	csa.Type = sa.Type
+	csa.NumChannels = int32(len(sa.Speakers))
+	copy(csa.speakers(), sa.Speakers)
+	return csa
+}
+
+// arrangement copies C arrangement into Go memory.
+func (sa *speakerArrangement) arrangement() *SpeakerArrangement {
+	if sa == nil {
+		return nil
+	}
+	return &SpeakerArrangement{
+		Type:     sa.Type,
+		Speakers: append([]Speaker(nil), sa.speakers()...),
+	}
+}
+
+// speakers returns slice that refers to C memory.
+func (sa *speakerArrangement) speakers() []Speaker {
+	if sa.NumChannels <= 0 {
+		return nil
+	}
+	p := unsafe.Pointer(uintptr(unsafe.Pointer(sa)) + unsafe.Sizeof(speakerArrangement{}))
+	return (*[maxSpeakers]Speaker)(p)[:sa.NumChannels:sa.NumChannels]
+}
+
+// free the allocated memory.
+func (sa *speakerArrangement) free() {
+	C.free(unsafe.Pointer(sa))
+}
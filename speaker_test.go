@@ -0,0 +1,36 @@
+package vst2_test
+
+import (
+	"testing"
+
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSpeakerArrangement(t *testing.T) {
+	sa := vst2.NewSpeakerArrangement(vst2.SpeakerArr51)
+	assert.Equal(t, vst2.SpeakerArr51, sa.Type)
+	assert.Equal(t, 6, sa.NumChannels())
+	expected := []vst2.SpeakerType{
+		vst2.SpeakerL,
+		vst2.SpeakerR,
+		vst2.SpeakerC,
+		vst2.SpeakerLfe,
+		vst2.SpeakerLs,
+		vst2.SpeakerRs,
+	}
+	for i, st := range expected {
+		assert.Equal(t, st, sa.Speakers[i].Type)
+		assert.Equal(t, st.String(), string(sa.Speakers[i].Name[:len(st.String())]))
+	}
+
+	assert.Equal(t, 12, vst2.NewSpeakerArrangement(vst2.SpeakerArr102).NumChannels())
+	assert.Nil(t, vst2.NewSpeakerArrangement(vst2.SpeakerArrUserDefined))
+
+	assert.Equal(t, vst2.SpeakerArrStereo, vst2.DefaultSpeakerArrangement(2).Type)
+	sa = vst2.DefaultSpeakerArrangement(10)
+	assert.Equal(t, vst2.SpeakerArrUserDefined, sa.Type)
+	assert.Equal(t, 10, sa.NumChannels())
+	assert.Nil(t, vst2.DefaultSpeakerArrangement(-1))
+}
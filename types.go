@@ -1,5 +1,10 @@
 package vst2
 
+import (
+	"strconv"
+	"strings"
+)
+
 const (
 	maxProgNameLen   = 24 // used for #effGetProgramName, #effSetProgramName, #effGetProgramNameIndexed
 	maxParamStrLen   = 8  // used for #effGetParamLabel, #effGetParamDisplay, #effGetParamName
@@ -123,11 +128,19 @@ const (
 )
 
 type (
-	// SpeakerArrangement contains information about a channel.
+	// SpeakerArrangement contains information about channels. It's
+	// copied into C-allocated variable-length structure when passed to
+	// plugin, so any number of speakers is supported.
 	SpeakerArrangement struct {
+		Type     SpeakerArrangementType
+		Speakers []Speaker
+	}
+
+	// speakerArrangement is a C layout of arrangement header. It's
+	// followed by NumChannels speakers.
+	speakerArrangement struct {
 		Type        SpeakerArrangementType
 		NumChannels int32
-		Speakers    [8]Speaker
 	}
 
 	// SpeakerArrangementType indicates how the channels are intended to be used in the plugin.
@@ -147,6 +160,12 @@ type (
 
 	// SpeakerType of particular speaker.
 	SpeakerType int32
+
+	// SpeakerConfig is a pair of input and output speaker arrangements.
+	SpeakerConfig struct {
+		In  *SpeakerArrangement
+		Out *SpeakerArrangement
+	}
 )
 
 const (
@@ -217,10 +236,8 @@ const (
 )
 
 const (
-	// SpeakerUndefined is undefined.
-	SpeakerUndefined SpeakerType = 0x7fffffff
 	// SpeakerM is Mono (M).
-	SpeakerM = iota
+	SpeakerM SpeakerType = iota
 	// SpeakerL is Left (L).
 	SpeakerL
 	// SpeakerR is Right (R).
@@ -239,8 +256,6 @@ const (
 	SpeakerRc
 	// SpeakerS is Surround (S).
 	SpeakerS
-	// SpeakerCs is Center of Surround (Cs) = Surround (S).
-	SpeakerCs = SpeakerS
 	// SpeakerSl is Side Left (Sl).
 	SpeakerSl
 	// SpeakerSr is Side Right (Sr).
@@ -261,6 +276,43 @@ const (
 	SpeakerTrr
 	// SpeakerLfe2 is Subbass 2 (Lfe2).
 	SpeakerLfe2
+
+	// SpeakerCs is Center of Surround (Cs) = Surround (S).
+	SpeakerCs = SpeakerS
+	// SpeakerUndefined is undefined.
+	SpeakerUndefined SpeakerType = 0x7fffffff
+)
+
+type (
+	// PinProperties describes plugin's input or output channel.
+	PinProperties struct {
+		Label           string
+		ShortLabel      string
+		Flags           PinPropertiesFlags
+		ArrangementType SpeakerArrangementType
+	}
+
+	// PinPropertiesFlags values.
+	PinPropertiesFlags int32
+
+	// pinProperties is a C layout of pin properties.
+	pinProperties struct {
+		label           [maxLabelLen]byte
+		flags           PinPropertiesFlags
+		arrangementType SpeakerArrangementType
+		shortLabel      [maxShortLabelLen]byte
+		future          [48]byte
+	}
+)
+
+const (
+	// PinIsActive is set if pin is active, ignored by host.
+	PinIsActive PinPropertiesFlags = 1 << iota
+	// PinIsStereo means that pin is first of a stereo pair.
+	PinIsStereo
+	// PinUseSpeaker means that PinProperties.ArrangementType is valid and
+	// can be used to get the wanted arrangement.
+	PinUseSpeaker
 )
 
 // EffectFlags values.
@@ -299,6 +351,159 @@ const (
 	effFlagsExtHasBuffer
 )
 
+// effectFlagNames are names of flags in bit order.
+var effectFlagNames = []struct {
+	flag EffectFlags
+	name string
+}{
+	{EffFlagsHasEditor, "HasEditor"},
+	{EffFlagsCanReplacing, "CanReplacing"},
+	{EffFlagsProgramChunks, "ProgramChunks"},
+	{EffFlagsIsSynth, "IsSynth"},
+	{EffFlagsNoSoundInStop, "NoSoundInStop"},
+	{EffFlagsCanDoubleReplacing, "CanDoubleReplacing"},
+}
+
+// Names returns names of flags that are set. Unknown and deprecated
+// flags are ignored.
+func (f EffectFlags) Names() []string {
+	var names []string
+	for _, n := range effectFlagNames {
+		if f&n.flag == n.flag {
+			names = append(names, n.name)
+		}
+	}
+	return names
+}
+
+func (f EffectFlags) String() string {
+	return strings.Join(f.Names(), "|")
+}
+
+// PluginCategory is returned by EffGetPlugCategory call.
+type PluginCategory int32
+
+const (
+	// PluginCategoryUnknown is returned when category is not implemented.
+	PluginCategoryUnknown PluginCategory = iota
+	// PluginCategoryEffect is a simple effect.
+	PluginCategoryEffect
+	// PluginCategorySynth is a VST instrument.
+	PluginCategorySynth
+	// PluginCategoryAnalysis is a scope, tuner, etc.
+	PluginCategoryAnalysis
+	// PluginCategoryMastering is a dynamics processor, etc.
+	PluginCategoryMastering
+	// PluginCategorySpacializer is a panner, etc.
+	PluginCategorySpacializer
+	// PluginCategoryRoomFx is a delay or reverb.
+	PluginCategoryRoomFx
+	// PluginCategorySurroundFx is a dedicated surround processor.
+	PluginCategorySurroundFx
+	// PluginCategoryRestoration is a denoiser, etc.
+	PluginCategoryRestoration
+	// PluginCategoryOfflineProcess is an offline processor.
+	PluginCategoryOfflineProcess
+	// PluginCategoryShell is a plugin that contains other plugins.
+	PluginCategoryShell
+	// PluginCategoryGenerator is a tone generator, etc.
+	PluginCategoryGenerator
+)
+
+var pluginCategoryNames = []string{
+	"Unknown",
+	"Effect",
+	"Synth",
+	"Analysis",
+	"Mastering",
+	"Spacializer",
+	"RoomFx",
+	"SurroundFx",
+	"Restoration",
+	"OfflineProcess",
+	"Shell",
+	"Generator",
+}
+
+func (c PluginCategory) String() string {
+	if c < 0 || int(c) >= len(pluginCategoryNames) {
+		return "PluginCategory(" + strconv.Itoa(int(c)) + ")"
+	}
+	return pluginCategoryNames[c]
+}
+
+// PluginCanDo are the strings passed in EffCanDo call to check
+// capabilities of plugin.
+type PluginCanDo string
+
+const (
+	// PluginCanSendEvents is set if plugin sends events to host.
+	PluginCanSendEvents PluginCanDo = "sendVstEvents"
+	// PluginCanSendMidiEvent is set if plugin sends MIDI events to host.
+	PluginCanSendMidiEvent PluginCanDo = "sendVstMidiEvent"
+	// PluginCanReceiveEvents is set if plugin receives events from host.
+	PluginCanReceiveEvents PluginCanDo = "receiveVstEvents"
+	// PluginCanReceiveMidiEvent is set if plugin receives MIDI events
+	// from host.
+	PluginCanReceiveMidiEvent PluginCanDo = "receiveVstMidiEvent"
+	// PluginCanReceiveTimeInfo is set if plugin uses time info.
+	PluginCanReceiveTimeInfo PluginCanDo = "receiveVstTimeInfo"
+	// PluginCanOffline is set if plugin supports offline processing.
+	PluginCanOffline PluginCanDo = "offline"
+	// PluginCanMidiProgramNames is set if plugin provides MIDI program
+	// names.
+	PluginCanMidiProgramNames PluginCanDo = "midiProgramNames"
+	// PluginCanBypass is set if plugin supports soft bypass.
+	PluginCanBypass PluginCanDo = "bypass"
+)
+
+// PluginCanDos returns all known plugin capabilities.
+func PluginCanDos() []PluginCanDo {
+	return []PluginCanDo{
+		PluginCanSendEvents,
+		PluginCanSendMidiEvent,
+		PluginCanReceiveEvents,
+		PluginCanReceiveMidiEvent,
+		PluginCanReceiveTimeInfo,
+		PluginCanOffline,
+		PluginCanMidiProgramNames,
+		PluginCanBypass,
+	}
+}
+
+// CanDoResponse is returned by EffCanDo call.
+type CanDoResponse int
+
+const (
+	// CanDoNo is returned if capability is not supported.
+	CanDoNo CanDoResponse = -1
+	// CanDoMaybe is returned if plugin doesn't know.
+	CanDoMaybe CanDoResponse = 0
+	// CanDoYes is returned if capability is supported.
+	CanDoYes CanDoResponse = 1
+)
+
+func (r CanDoResponse) String() string {
+	switch r {
+	case CanDoNo:
+		return "no"
+	case CanDoYes:
+		return "yes"
+	default:
+		return "maybe"
+	}
+}
+
+// PanLawType is passed in EffSetPanLaw call.
+type PanLawType int32
+
+const (
+	// PanLawLinear is L = pan * M; R = (1 - pan) * M.
+	PanLawLinear PanLawType = iota
+	// PanLawEqualPower is L = pow(pan, 0.5) * M; R = pow((1 - pan), 0.5) * M.
+	PanLawEqualPower
+)
+
 // ProcessLevels are used as result for in HostGetCurrentProcessLevel call.
 // It tells the plugin in which thread host is right now.
 type ProcessLevels int32
@@ -315,3 +520,105 @@ const (
 	// ProcessLevelOffline is returned when in offline processing and thus in user thread.
 	ProcessLevelOffline
 )
+
+// AutomationState is returned as result for HostGetAutomationState call.
+type AutomationState int32
+
+const (
+	// AutomationStateUnsupported is returned when not supported by host.
+	AutomationStateUnsupported AutomationState = iota
+	// AutomationStateOff is returned when automation is off.
+	AutomationStateOff
+	// AutomationStateRead is returned when automation is read.
+	AutomationStateRead
+	// AutomationStateWrite is returned when automation is written.
+	AutomationStateWrite
+	// AutomationStateReadWrite is returned when automation is read and written.
+	AutomationStateReadWrite
+)
+
+// AudioFileFlags values, used in offline processing.
+type AudioFileFlags int32
+
+const (
+	// AudioFileReadOnly is set by host.
+	AudioFileReadOnly AudioFileFlags = 1 << iota
+	// AudioFileNoRateConversion is set by host.
+	AudioFileNoRateConversion
+	// AudioFileNoChannelChange is set by host.
+	AudioFileNoChannelChange
+	_
+	_
+	_
+	_
+	_
+	_
+	_
+	// AudioFileCanProcessSelection is set by plugin if it can process selection.
+	AudioFileCanProcessSelection
+	// AudioFileNoCrossfade is set by plugin if crossfade isn't needed.
+	AudioFileNoCrossfade
+	// AudioFileWantRead is set by plugin if it wants to read.
+	AudioFileWantRead
+	// AudioFileWantWrite is set by plugin if it wants to write.
+	AudioFileWantWrite
+	// AudioFileWantWriteMarker is set by plugin if it wants to write markers.
+	AudioFileWantWriteMarker
+	// AudioFileWantMoveCursor is set by plugin if it wants to move cursor.
+	AudioFileWantMoveCursor
+	// AudioFileWantSelect is set by plugin if it wants to select.
+	AudioFileWantSelect
+)
+
+// OfflineTaskFlags values, used in offline processing.
+type OfflineTaskFlags int32
+
+const (
+	// OfflineInvalidParameter is set by host.
+	OfflineInvalidParameter OfflineTaskFlags = 1 << iota
+	// OfflineNewFile is set by host.
+	OfflineNewFile
+	_
+	_
+	_
+	_
+	_
+	_
+	_
+	_
+	// OfflinePlugError is set by plugin if error occurred.
+	OfflinePlugError
+	// OfflineInterleavedAudio is set by plugin if audio is interleaved.
+	OfflineInterleavedAudio
+	// OfflineTempOutputFile is set by plugin if output is a temporary file.
+	OfflineTempOutputFile
+	// OfflineFloatOutputFile is set by plugin if output is float file.
+	OfflineFloatOutputFile
+	// OfflineRandomWrite is set by plugin if it writes randomly.
+	OfflineRandomWrite
+	// OfflineStretch is set by plugin if it stretches audio.
+	OfflineStretch
+	// OfflineNoThread is set by plugin if it processes without thread.
+	OfflineNoThread
+)
+
+// OfflineOption is passed as value in HostOfflineRead and
+// HostOfflineWrite calls.
+type OfflineOption int32
+
+const (
+	// OfflineAudio reads and writes audio samples.
+	OfflineAudio OfflineOption = iota
+	// OfflinePeaks reads and writes peaks.
+	OfflinePeaks
+	// OfflineParameter reads and writes parameters.
+	OfflineParameter
+	// OfflineMarker reads and writes markers.
+	OfflineMarker
+	// OfflineCursor reads and writes cursor position.
+	OfflineCursor
+	// OfflineSelection reads and writes selection.
+	OfflineSelection
+	// OfflineQueryFiles queries files.
+	OfflineQueryFiles
+)
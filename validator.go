@@ -0,0 +1,549 @@
+package vst2
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Default values of validator settings.
+const (
+	DefaultValidationCycles     = 10
+	DefaultValidationIterations = 100
+	DefaultValidationTolerance  = 1e-6
+)
+
+var (
+	// DefaultValidationSampleRates are used if Validator.SampleRates is
+	// not set.
+	DefaultValidationSampleRates = []int{44100, 48000, 96000, 22050}
+	// DefaultValidationBufferSizes are used if Validator.BufferSizes is
+	// not set.
+	DefaultValidationBufferSizes = []int{512, 1, 64, 4096}
+)
+
+// Minimal normal values, smaller non-zero values are denormals.
+const (
+	minNormal32 = 0x1p-126
+	minNormal64 = 0x1p-1022
+)
+
+type (
+	// Validator runs conformance checks against plugins of VST library.
+	// Every check loads fresh plugin instance. Crash of plugin terminates
+	// the process, so validator should be run in a separate process if
+	// host must survive it. Zero value uses default settings.
+	Validator struct {
+		// SampleRates are set in configuration check.
+		SampleRates []int
+		// BufferSizes are set in configuration check. The first one is
+		// used in other checks.
+		BufferSizes []int
+		// Cycles is a number of open/close cycles.
+		Cycles int
+		// Iterations is a number of processed blocks and parameter
+		// changes per check.
+		Iterations int
+		// Seed of random parameters and noise.
+		Seed int64
+		// Tolerance is a maximum difference of outputs in chunk round
+		// trip check.
+		Tolerance float64
+	}
+
+	// ValidationReport contains results of all checks.
+	ValidationReport struct {
+		Path    string
+		Results []ValidationResult
+	}
+
+	// ValidationResult is a result of single check. Err is nil if check
+	// passed.
+	ValidationResult struct {
+		Check    string
+		Err      error
+		Duration time.Duration
+	}
+
+	// validation holds state of running check.
+	validation struct {
+		Validator
+		vst        *VST
+		rand       *rand.Rand
+		sampleRate int
+		bufferSize int
+	}
+
+	// validationBuffers are used to process plugin with any precision.
+	validationBuffers struct {
+		in       DoubleBuffer
+		out      DoubleBuffer
+		floatIn  FloatBuffer
+		floatOut FloatBuffer
+	}
+)
+
+// Passed returns true if all checks passed.
+func (r ValidationReport) Passed() bool {
+	for _, result := range r.Results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate runs all checks against plugin of the library.
+func (v Validator) Validate(vst *VST) ValidationReport {
+	checks := []struct {
+		name string
+		fn   func(*validation) error
+	}{
+		{"open close", (*validation).openClose},
+		{"configuration", (*validation).configuration},
+		{"parameter sweep", (*validation).parameterSweep},
+		{"chunk round trip", (*validation).chunkRoundTrip},
+		{"signals", (*validation).signals},
+		{"zero-size blocks", (*validation).zeroSize},
+		{"concurrent parameters", (*validation).concurrentParameters},
+	}
+	v.defaults()
+	report := ValidationReport{Path: vst.Path}
+	for _, c := range checks {
+		s := validation{
+			Validator:  v,
+			vst:        vst,
+			rand:       rand.New(rand.NewSource(v.Seed)),
+			sampleRate: v.SampleRates[0],
+			bufferSize: v.BufferSizes[0],
+		}
+		start := time.Now()
+		err := c.fn(&s)
+		report.Results = append(report.Results, ValidationResult{
+			Check:    c.name,
+			Err:      err,
+			Duration: time.Since(start),
+		})
+	}
+	return report
+}
+
+func (v *Validator) defaults() {
+	if len(v.SampleRates) == 0 {
+		v.SampleRates = DefaultValidationSampleRates
+	}
+	if len(v.BufferSizes) == 0 {
+		v.BufferSizes = DefaultValidationBufferSizes
+	}
+	if v.Cycles == 0 {
+		v.Cycles = DefaultValidationCycles
+	}
+	if v.Iterations == 0 {
+		v.Iterations = DefaultValidationIterations
+	}
+	if v.Tolerance == 0 {
+		v.Tolerance = DefaultValidationTolerance
+	}
+}
+
+// load returns resumed plugin.
+func (s *validation) load() (*Plugin, error) {
+	p := s.vst.Load(s.callback())
+	if p == nil {
+		return nil, errors.New("failed to load plugin")
+	}
+	if err := s.configure(p); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// configure sets current sample rate and buffer size and resumes plugin.
+func (s *validation) configure(p *Plugin) error {
+	if err := p.SetSampleRate(s.sampleRate); err != nil {
+		return fmt.Errorf("failed to set sample rate %d: %w", s.sampleRate, err)
+	}
+	if err := p.SetBufferSize(s.bufferSize); err != nil {
+		return fmt.Errorf("failed to set buffer size %d: %w", s.bufferSize, err)
+	}
+	if err := p.Start(); err != nil {
+		return fmt.Errorf("failed to resume: %w", err)
+	}
+	return nil
+}
+
+func (s *validation) callback() HostCallbackFunc {
+	return func(opcode HostOpcode, index Index, value Value, ptr Ptr, opt Opt) Return {
+		switch opcode {
+		case HostGetSampleRate:
+			return Return(s.sampleRate)
+		case HostGetBlockSize:
+			return Return(s.bufferSize)
+		case HostGetCurrentProcessLevel:
+			return Return(ProcessLevelRealtime)
+		}
+		return 0
+	}
+}
+
+func newValidationBuffers(p *Plugin, bufferSize int) validationBuffers {
+	numChannels := max(1, max(p.NumInputs(), p.NumOutputs()))
+	return validationBuffers{
+		in:       NewDoubleBuffer(numChannels, bufferSize),
+		out:      NewDoubleBuffer(numChannels, bufferSize),
+		floatIn:  NewFloatBuffer(numChannels, bufferSize),
+		floatOut: NewFloatBuffer(numChannels, bufferSize),
+	}
+}
+
+// process processes n samples of input and checks the output.
+func (b validationBuffers) process(p *Plugin, n int) error {
+	out := b.out.slice(n)
+	if err := processDouble(p, b.in.slice(n), out, b.floatIn, b.floatOut); err != nil {
+		return err
+	}
+	minNormal := minNormal64
+	if !p.CanProcessFloat64() {
+		minNormal = minNormal32
+	}
+	for i := 0; i < out.numChannels; i++ {
+		if err := checkSamples(out.Channel(i), minNormal); err != nil {
+			return fmt.Errorf("channel %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// noise fills input with random signal.
+func (b validationBuffers) noise(r *rand.Rand) {
+	for i := 0; i < b.in.numChannels; i++ {
+		ch := b.in.Channel(i)
+		for j := range ch {
+			ch[j] = r.Float64() - 0.5
+		}
+	}
+}
+
+func (b validationBuffers) free() {
+	b.in.Free()
+	b.out.Free()
+	b.floatIn.Free()
+	b.floatOut.Free()
+}
+
+// checkSamples returns error if samples contain NaN, Inf or denormals.
+func checkSamples(samples []float64, minNormal float64) error {
+	for i, v := range samples {
+		switch {
+		case math.IsNaN(v):
+			return fmt.Errorf("sample %d is NaN", i)
+		case math.IsInf(v, 0):
+			return fmt.Errorf("sample %d is Inf", i)
+		case v != 0 && math.Abs(v) < minNormal:
+			return fmt.Errorf("sample %d is denormal: %g", i, v)
+		}
+	}
+	return nil
+}
+
+// openClose loads, processes and closes plugin multiple times.
+func (s *validation) openClose() error {
+	for i := 0; i < s.Cycles; i++ {
+		p, err := s.load()
+		if err != nil {
+			return fmt.Errorf("cycle %d: %w", i, err)
+		}
+		b := newValidationBuffers(p, s.bufferSize)
+		b.noise(s.rand)
+		err = b.process(p, s.bufferSize)
+		b.free()
+		if e := p.Close(); err == nil {
+			err = e
+		}
+		if err != nil {
+			return fmt.Errorf("cycle %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// configuration suspends plugin, changes sample rate and buffer size
+// and processes full blocks.
+func (s *validation) configuration() error {
+	p, err := s.load()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	maxSize := 0
+	for _, size := range s.BufferSizes {
+		maxSize = max(maxSize, size)
+	}
+	b := newValidationBuffers(p, maxSize)
+	defer b.free()
+	for _, sampleRate := range s.SampleRates {
+		for _, bufferSize := range s.BufferSizes {
+			s.sampleRate, s.bufferSize = sampleRate, bufferSize
+			if err := p.Stop(); err != nil {
+				return fmt.Errorf("failed to suspend: %w", err)
+			}
+			if err := s.configure(p); err != nil {
+				return err
+			}
+			b.noise(s.rand)
+			if err := b.process(p, s.bufferSize); err != nil {
+				return fmt.Errorf("sample rate %d buffer size %d: %w", s.sampleRate, s.bufferSize, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parameterSweep sets random values to random parameters and processes
+// block after every change.
+func (s *validation) parameterSweep() error {
+	p, err := s.load()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	if p.NumParams() == 0 {
+		return nil
+	}
+	b := newValidationBuffers(p, s.bufferSize)
+	defer b.free()
+	for i := 0; i < s.Iterations; i++ {
+		index, value := s.rand.Intn(p.NumParams()), s.rand.Float32()
+		if err := p.SetParameter(index, value); err != nil {
+			return err
+		}
+		if v := p.Parameter(index); !(v >= 0 && v <= 1) {
+			return fmt.Errorf("parameter %d value %v is out of range after set to %v", index, v, value)
+		}
+		p.ParamDisplay(index)
+		b.noise(s.rand)
+		if err := b.process(p, s.bufferSize); err != nil {
+			return fmt.Errorf("parameter %d set to %v: %w", index, value, err)
+		}
+	}
+	return nil
+}
+
+// chunkRoundTrip saves state of plugin with random parameters, restores
+// it into another instance and compares outputs of both instances.
+func (s *validation) chunkRoundTrip() error {
+	p, err := s.load()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	for i := 0; i < p.NumParams(); i++ {
+		if err := p.SetParameter(i, s.rand.Float32()); err != nil {
+			return err
+		}
+	}
+	chunks := p.Flags()&EffFlagsProgramChunks != 0
+	var (
+		chunk  []byte
+		params = make([]float32, p.NumParams())
+	)
+	if chunks {
+		if chunk = p.Chunk(false); chunk == nil {
+			return errors.New("plugin supports chunks, but returned empty chunk")
+		}
+	} else {
+		for i := range params {
+			params[i] = p.Parameter(i)
+		}
+	}
+
+	restored, err := s.load()
+	if err != nil {
+		return err
+	}
+	defer restored.Close()
+	if err := restored.Stop(); err != nil {
+		return err
+	}
+	if chunks {
+		if err := restored.SetChunk(chunk, false); err != nil {
+			return err
+		}
+	} else {
+		for i, v := range params {
+			if err := restored.SetParameter(i, v); err != nil {
+				return err
+			}
+		}
+	}
+	if err := restored.Start(); err != nil {
+		return err
+	}
+	for i := 0; i < p.NumParams(); i++ {
+		if want, got := p.Parameter(i), restored.Parameter(i); want != got {
+			return fmt.Errorf("parameter %d is %v after restore, want %v", i, got, want)
+		}
+	}
+
+	b, rb := newValidationBuffers(p, s.bufferSize), newValidationBuffers(restored, s.bufferSize)
+	defer b.free()
+	defer rb.free()
+	for i := 0; i < s.Iterations; i++ {
+		b.noise(s.rand)
+		for c := 0; c < min(b.in.numChannels, rb.in.numChannels); c++ {
+			copy(rb.in.Channel(c), b.in.Channel(c))
+		}
+		if err := b.process(p, s.bufferSize); err != nil {
+			return err
+		}
+		if err := rb.process(restored, s.bufferSize); err != nil {
+			return err
+		}
+		for c := 0; c < min(b.out.numChannels, rb.out.numChannels); c++ {
+			want, got := b.out.Channel(c), rb.out.Channel(c)
+			for j := range want {
+				if d := math.Abs(want[j] - got[j]); d > s.Tolerance {
+					return fmt.Errorf("block %d channel %d sample %d differs by %g after restore", i, c, j, d)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// signals processes silence, noise and impulses with every supported
+// precision.
+func (s *validation) signals() error {
+	p, err := s.load()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	b := newValidationBuffers(p, s.bufferSize)
+	defer b.free()
+	fills := []struct {
+		name string
+		fill func(i int)
+	}{
+		{"silence", func(int) { b.fill(0) }},
+		{"noise", func(int) { b.noise(s.rand) }},
+		{"impulse", func(i int) {
+			b.fill(0)
+			if i == 0 {
+				for c := 0; c < b.in.numChannels; c++ {
+					b.in.Channel(c)[0] = 1
+				}
+			}
+		}},
+	}
+	for _, f := range fills {
+		for i := 0; i < s.Iterations; i++ {
+			f.fill(i)
+			if err := b.process(p, s.bufferSize); err != nil {
+				return fmt.Errorf("%s block %d: %w", f.name, i, err)
+			}
+			if !p.CanProcessFloat32() || !p.CanProcessFloat64() {
+				continue
+			}
+			// process with single precision as well.
+			fin, fout := b.floatIn.slice(s.bufferSize), b.floatOut.slice(s.bufferSize)
+			for c := 0; c < fin.numChannels; c++ {
+				src, dst := b.in.Channel(c), fin.Channel(c)
+				for j := range dst {
+					dst[j] = float32(src[j])
+				}
+			}
+			if err := p.ProcessFloat(fin, fout); err != nil {
+				return err
+			}
+			for c := 0; c < fout.numChannels; c++ {
+				ch := fout.Channel(c)
+				samples := make([]float64, len(ch))
+				for j, v := range ch {
+					samples[j] = float64(v)
+				}
+				if err := checkSamples(samples, minNormal32); err != nil {
+					return fmt.Errorf("%s block %d single precision channel %d: %w", f.name, i, c, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fill sets all input samples to value.
+func (b validationBuffers) fill(v float64) {
+	for i := 0; i < b.in.numChannels; i++ {
+		ch := b.in.Channel(i)
+		for j := range ch {
+			ch[j] = v
+		}
+	}
+}
+
+// zeroSize processes empty blocks between regular ones.
+func (s *validation) zeroSize() error {
+	p, err := s.load()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	b := newValidationBuffers(p, s.bufferSize)
+	defer b.free()
+	for i := 0; i < s.Iterations; i++ {
+		if err := b.process(p, 0); err != nil {
+			return fmt.Errorf("empty block %d: %w", i, err)
+		}
+		b.noise(s.rand)
+		if err := b.process(p, s.bufferSize); err != nil {
+			return fmt.Errorf("block %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// concurrentParameters sets parameters from another goroutine while
+// processing.
+func (s *validation) concurrentParameters() error {
+	p, err := s.load()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	if p.NumParams() == 0 {
+		return nil
+	}
+	b := newValidationBuffers(p, s.bufferSize)
+	defer b.free()
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+		r    = rand.New(rand.NewSource(s.rand.Int63()))
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				p.SetParameter(r.Intn(p.NumParams()), r.Float32())
+			}
+		}
+	}()
+	defer wg.Wait()
+	defer close(done)
+	for i := 0; i < s.Iterations; i++ {
+		b.noise(s.rand)
+		if err := b.process(p, s.bufferSize); err != nil {
+			return fmt.Errorf("block %d: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package vst2_test
+
+import (
+	"testing"
+
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidator(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	validator := vst2.Validator{
+		SampleRates: []int{44100, 96000},
+		BufferSizes: []int{64, 1},
+		Cycles:      2,
+		Iterations:  10,
+	}
+	report := validator.Validate(v)
+	for _, r := range report.Results {
+		assert.Nil(t, r.Err, r.Check)
+	}
+	assert.True(t, report.Passed())
+}
@@ -0,0 +1,181 @@
+package vst2
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+#include "vst.h"
+*/
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"pipelined.dev/signal"
+)
+
+// maxPendingBlocks limits input that plugin didn't consume, in blocks of
+// plugin buffer size.
+const maxPendingBlocks = 16
+
+// ErrVariableIONotSupported is returned when plugin doesn't handle
+// EffProcessVarIo call.
+var ErrVariableIONotSupported = errors.New("plugin doesn't support variable io processing")
+
+// variableIO processes audio with EffProcessVarIo call. Plugin can
+// consume and produce different number of samples, so leftovers are
+// accumulated across blocks. Output is silent until plugin produced
+// enough samples to fill the whole block, number of these silent samples
+// is a latency of processing.
+type variableIO struct {
+	numChannels int
+	// bufferSize is a maximum number of samples passed to plugin in one
+	// call.
+	bufferSize int
+	vio        *C.VariableIO
+	// processed contains C-allocated counters of processed samples.
+	processed *[2]C.int32_t
+	// C buffers, input has buffer size capacity.
+	in, out unsafe.Pointer
+	outCap  int
+	// samples that plugin didn't consume yet. Capacity is allocated
+	// once and limits pending input.
+	pendingIn [][]float64
+	// block is reused to pass parts of processed signal.
+	block signal.Float64
+	// samples that plugin produced, but weren't sent yet.
+	pendingOut signal.Float64
+	// latency is a number of silent samples sent before the first
+	// produced one.
+	latency int
+	started bool
+}
+
+func newVariableIO(numChannels, bufferSize int) *variableIO {
+	v := variableIO{
+		numChannels: numChannels,
+		bufferSize:  bufferSize,
+		vio:         (*C.VariableIO)(C.calloc(1, C.sizeof_VariableIO)),
+		processed:   (*[2]C.int32_t)(C.calloc(2, C.sizeof_int32_t)),
+		in:          newFloatChannels(numChannels, bufferSize),
+		pendingIn:   make([][]float64, numChannels),
+		pendingOut:  signal.Float64Buffer(numChannels, 0),
+		block:       make(signal.Float64, numChannels),
+	}
+	for c := range v.pendingIn {
+		v.pendingIn[c] = make([]float64, 0, maxPendingBlocks*bufferSize)
+	}
+	v.vio.numSamplesInputProcessed = &v.processed[0]
+	v.vio.numSamplesOutputProcessed = &v.processed[1]
+	return &v
+}
+
+// slice returns part of signal without copying samples. Returned value
+// is valid until the next call.
+func (v *variableIO) slice(s signal.Float64, off, n int) signal.Float64 {
+	for c := range v.block {
+		v.block[c] = s[c][off : off+n]
+	}
+	return v.block
+}
+
+// process passes block to plugin and replaces it with produced samples.
+// Block must not exceed buffer size. Error is returned if plugin doesn't
+// support variable io or doesn't consume input.
+func (v *variableIO) process(p *Plugin, block signal.Float64) error {
+	size := block.Size()
+	if pending := len(v.pendingIn[0]); pending+size > cap(v.pendingIn[0]) {
+		return fmt.Errorf("plugin didn't consume %d pending samples", pending)
+	}
+	for c := range v.pendingIn {
+		v.pendingIn[c] = append(v.pendingIn[c], block[c]...)
+	}
+	// plugin never gets more samples than its buffer size.
+	numIn := min(len(v.pendingIn[0]), v.bufferSize)
+	// plugin reports expected ratio, reserve twice more for output.
+	ratio := float64(p.effect.ioRatio)
+	if ratio <= 0 {
+		ratio = 1
+	}
+	numOut := max(int(2*ratio*float64(numIn)), size)
+	v.reserve(numOut)
+
+	in := floatChannels(v.in, v.numChannels)
+	for c := range in {
+		row := (*[1 << 30]C.float)(unsafe.Pointer(in[c]))
+		for i, s := range v.pendingIn[c][:numIn] {
+			row[i] = C.float(s)
+		}
+	}
+	v.vio.inputs = (**C.float)(v.in)
+	v.vio.outputs = (**C.float)(v.out)
+	v.vio.numSamplesInput = C.int32_t(numIn)
+	v.vio.numSamplesOutput = C.int32_t(numOut)
+	v.processed[0], v.processed[1] = 0, 0
+	if p.Dispatch(EffProcessVarIo, 0, 0, Ptr(v.vio), 0) == 0 {
+		return ErrVariableIONotSupported
+	}
+
+	consumed := clamp(int(v.processed[0]), 0, numIn)
+	produced := clamp(int(v.processed[1]), 0, numOut)
+	for c := range v.pendingIn {
+		n := copy(v.pendingIn[c], v.pendingIn[c][consumed:])
+		v.pendingIn[c] = v.pendingIn[c][:n]
+	}
+	out := floatChannels(v.out, v.numChannels)
+	for c := range out {
+		row := (*[1 << 30]C.float)(unsafe.Pointer(out[c]))
+		for i := 0; i < produced; i++ {
+			v.pendingOut[c] = append(v.pendingOut[c], float64(row[i]))
+		}
+	}
+
+	// send silence until enough samples produced.
+	if v.pendingOut.Size() < size {
+		for c := range block {
+			for i := range block[c] {
+				block[c][i] = 0
+			}
+		}
+		if !v.started {
+			v.latency += size
+		}
+		return nil
+	}
+	v.started = true
+	for c := range block {
+		copy(block[c], v.pendingOut[c][:size])
+		n := copy(v.pendingOut[c], v.pendingOut[c][size:])
+		v.pendingOut[c] = v.pendingOut[c][:n]
+	}
+	return nil
+}
+
+// reserve grows C output buffer to fit provided number of samples.
+// Capacity is at least doubled, so buffer isn't reallocated on every
+// block.
+func (v *variableIO) reserve(numOut int) {
+	if numOut > v.outCap {
+		freeFloatChannels(v.out, v.numChannels)
+		v.outCap = max(numOut, 2*v.outCap)
+		v.out = newFloatChannels(v.numChannels, v.outCap)
+	}
+}
+
+// free the allocated memory.
+func (v *variableIO) free() {
+	freeFloatChannels(v.in, v.numChannels)
+	freeFloatChannels(v.out, v.numChannels)
+	C.free(unsafe.Pointer(v.processed))
+	C.free(unsafe.Pointer(v.vio))
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,48 @@
+package vst2_test
+
+import (
+	"errors"
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariableIO(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	const blockSize = 8
+	// blocks larger than buffer size are split.
+	for _, maxBlockSize := range []int{0, blockSize / 2} {
+		p := vst2.Processor{VST: v, VariableIO: true, MaxBlockSize: maxBlockSize}
+		fn, err := p.Process("", sampleRate, 2)
+		require.Nil(t, err)
+
+		var out []float64
+		for i := 0; i < 16; i++ {
+			block := signal.Float64Buffer(2, blockSize)
+			for j := range block[0] {
+				block[0][j], block[1][j] = 1, 1
+			}
+			err := fn(block)
+			if errors.Is(err, vst2.ErrVariableIONotSupported) {
+				p.Flush("")
+				t.Skip("plugin doesn't support variable io")
+			}
+			require.Nil(t, err)
+			out = append(out, block[0]...)
+		}
+		latency := p.Latency() - p.Plugin().InitialDelay()
+		require.True(t, latency < len(out), "no samples produced with max block size %d", maxBlockSize)
+		for _, s := range out[:latency] {
+			assert.Equal(t, 0.0, s)
+		}
+		assert.NotEqual(t, 0.0, out[latency])
+		require.Nil(t, p.Flush(""))
+	}
+}
@@ -0,0 +1,191 @@
+package vst2
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"pipelined.dev/signal"
+)
+
+type (
+	// RenderVerifier checks if plugin renders identically across runs.
+	// Input is rendered twice with set up instances and once with
+	// instance restored from state of the first one.
+	RenderVerifier struct {
+		VST        *VST
+		SampleRate signal.SampleRate
+		// Configure is called for every processor before plugin is
+		// loaded. It can set automation, buffer size and other settings.
+		Configure func(*Processor)
+		// Setup is called with loaded plugin before the first and the
+		// second renders. It can set parameters or load presets. State
+		// of plugin after setup is restored for the third render.
+		Setup func(*Plugin) error
+		// Clock of processors. If nil, SampleClock that starts at Unix
+		// epoch is used, so wall-clock time doesn't affect renders.
+		Clock Clock
+	}
+
+	// RenderReport contains differences of renders.
+	RenderReport struct {
+		// Repeated is a difference between renders of set up instances.
+		Repeated RenderDiff
+		// Restored is a difference between render of set up instance
+		// and instance with restored state.
+		Restored RenderDiff
+	}
+
+	// RenderDiff is a sample-level difference of two renders.
+	RenderDiff struct {
+		// Samples is a number of samples that differ.
+		Samples int
+		// MaxDeviation is a maximum absolute difference of samples.
+		MaxDeviation float64
+		// Channel and Sample point to the first differing sample. Both
+		// are -1 if renders are identical.
+		Channel int
+		Sample  int
+	}
+)
+
+// Identical returns true if renders don't differ.
+func (r RenderReport) Identical() bool {
+	return r.Repeated.Identical() && r.Restored.Identical()
+}
+
+// Identical returns true if renders don't differ.
+func (d RenderDiff) Identical() bool {
+	return d.Samples == 0
+}
+
+func (d RenderDiff) String() string {
+	if d.Identical() {
+		return "identical"
+	}
+	return fmt.Sprintf("%d samples differ, max deviation %g, first at channel %d sample %d", d.Samples, d.MaxDeviation, d.Channel, d.Sample)
+}
+
+// CompareRenders returns difference of two signals. Missing channels and
+// samples are compared as zeros.
+func CompareRenders(a, b signal.Float64) RenderDiff {
+	d := RenderDiff{Channel: -1, Sample: -1}
+	numChannels, size := max(a.NumChannels(), b.NumChannels()), max(a.Size(), b.Size())
+	// samples are compared in time order to find the first difference.
+	for j := 0; j < size; j++ {
+		for i := 0; i < numChannels; i++ {
+			v, w := sample(a, i, j), sample(b, i, j)
+			if v == w {
+				continue
+			}
+			if d.Samples == 0 {
+				d.Channel, d.Sample = i, j
+			}
+			d.Samples++
+			if dev := math.Abs(v - w); dev > d.MaxDeviation || math.IsNaN(dev) {
+				d.MaxDeviation = dev
+			}
+		}
+	}
+	return d
+}
+
+// sample returns value of signal or zero if it's out of range.
+func sample(s signal.Float64, channel, pos int) float64 {
+	if channel >= len(s) || pos >= len(s[channel]) {
+		return 0
+	}
+	return s[channel][pos]
+}
+
+// Verify renders input three times and compares renders.
+func (v RenderVerifier) Verify(in signal.Float64) (RenderReport, error) {
+	reference, state, err := v.render(in, nil)
+	if err != nil {
+		return RenderReport{}, fmt.Errorf("failed to render reference: %w", err)
+	}
+	repeated, _, err := v.render(in, nil)
+	if err != nil {
+		return RenderReport{}, fmt.Errorf("failed to render repeated: %w", err)
+	}
+	restored, _, err := v.render(in, &state)
+	if err != nil {
+		return RenderReport{}, fmt.Errorf("failed to render restored: %w", err)
+	}
+	return RenderReport{
+		Repeated: CompareRenders(reference, repeated),
+		Restored: CompareRenders(reference, restored),
+	}, nil
+}
+
+// render processes copy of input with new processor. If restore is nil,
+// plugin is set up and its state before processing is returned.
+// Otherwise restore is applied to plugin instead of setup.
+func (v RenderVerifier) render(in signal.Float64, restore *SlotState) (signal.Float64, SlotState, error) {
+	clock := v.Clock
+	if clock == nil {
+		clock = SampleClock{Start: time.Unix(0, 0)}
+	}
+	p := Processor{VST: v.VST, Clock: clock}
+	if v.Configure != nil {
+		v.Configure(&p)
+	}
+	if restore != nil {
+		p.Chunk = restore.Chunk
+		// parameters are queued and applied before the first block.
+		if n := len(restore.Params); n > p.ParameterQueueSize && n > DefaultParameterQueueSize {
+			p.ParameterQueueSize = n
+		}
+	}
+	fn, err := p.Process("", v.SampleRate, in.NumChannels())
+	if err != nil {
+		return nil, SlotState{}, err
+	}
+	var state SlotState
+	if restore != nil {
+		err = v.restore(&p, *restore)
+	} else {
+		state, err = v.setup(p.Plugin())
+	}
+	if err != nil {
+		p.Flush("")
+		return nil, SlotState{}, err
+	}
+	out := signal.Float64(make([][]float64, in.NumChannels()))
+	for i := range out {
+		out[i] = append([]float64(nil), in[i]...)
+	}
+	err = fn(out)
+	if e := p.Flush(""); err == nil {
+		err = e
+	}
+	return out, state, err
+}
+
+// setup calls Setup with plugin and returns plugin state after that.
+func (v RenderVerifier) setup(p *Plugin) (SlotState, error) {
+	if v.Setup != nil {
+		if err := v.Setup(p); err != nil {
+			return SlotState{}, fmt.Errorf("failed to set up plugin: %w", err)
+		}
+	}
+	var state SlotState
+	if state.Chunk = p.Chunk(false); state.Chunk == nil {
+		state.Params = make([]float32, p.NumParams())
+		for i := range state.Params {
+			state.Params[i] = p.Parameter(i)
+		}
+	}
+	return state, nil
+}
+
+// restore queues saved parameters. Chunk is set by processor when
+// plugin is opened.
+func (v RenderVerifier) restore(p *Processor, state SlotState) error {
+	for i, value := range state.Params {
+		if err := p.SetParameter(i, value); err != nil {
+			return fmt.Errorf("failed to restore plugin: %w", err)
+		}
+	}
+	return nil
+}
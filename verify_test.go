@@ -0,0 +1,54 @@
+package vst2_test
+
+import (
+	"math"
+	"testing"
+
+	"pipelined.dev/signal"
+	"pipelined.dev/vst2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareRenders(t *testing.T) {
+	a := signal.Float64{{0, 1, 2, 3}, {0, 1, 2, 3}}
+	d := vst2.CompareRenders(a, signal.Float64{{0, 1, 2, 3}, {0, 1, 2, 3}})
+	assert.True(t, d.Identical())
+	assert.Equal(t, -1, d.Sample)
+
+	d = vst2.CompareRenders(a, signal.Float64{{0, 1, 2, 4}, {0, 1, 1.5, 3}})
+	assert.Equal(t, 2, d.Samples)
+	assert.Equal(t, 1.0, d.MaxDeviation)
+	assert.Equal(t, 1, d.Channel)
+	assert.Equal(t, 2, d.Sample)
+
+	d = vst2.CompareRenders(a, signal.Float64{{0, 1, 2}})
+	assert.Equal(t, 4, d.Samples)
+}
+
+func TestRenderVerifier(t *testing.T) {
+	v, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer v.Close()
+
+	in := signal.Float64{make([]float64, 1000), make([]float64, 1000)}
+	for i := range in[0] {
+		in[0][i] = math.Sin(float64(i) / 10)
+		in[1][i] = math.Cos(float64(i) / 10)
+	}
+	verifier := vst2.RenderVerifier{
+		VST:        v,
+		SampleRate: sampleRate,
+		Configure: func(p *vst2.Processor) {
+			p.MaxBlockSize = 64
+		},
+		Setup: func(p *vst2.Plugin) error {
+			// restored instance must pick up non-default value.
+			return p.SetParameter(0, 0.25)
+		},
+	}
+	report, err := verifier.Verify(in)
+	require.Nil(t, err)
+	assert.True(t, report.Identical(), "repeated: %v restored: %v", report.Repeated, report.Restored)
+}
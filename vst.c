@@ -24,4 +24,13 @@ void processDouble(Effect *effect, int numChannels, int blocksize, double ** inp
 // Bridge to call process replacing function of loaded plugin
 void processFloat(Effect *effect, int numChannels, int blocksize, float **inputs, float **outputs){
 	effect -> processReplacing(effect, inputs, outputs, blocksize);
+}
+// Bridge to call set parameter function of loaded plugin
+void setParameter(Effect *effect, int index, float value){
+	effect->setParameter(effect, index, value);
+}
+
+// Bridge to call get parameter function of loaded plugin
+float getParameter(Effect *effect, int index){
+	return effect->getParameter(effect, index);
 }
\ No newline at end of file
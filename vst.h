@@ -72,6 +72,146 @@ struct Effect
 	char future[56];
 };
 
+// AudioFile describes an audio file for offline processing.
+typedef struct AudioFile
+{
+	// AudioFileFlags values.
+	int32_t flags;
+	// Reserved for host.
+	void* hostOwned;
+	// Reserved for plugin.
+	void* plugOwned;
+	// File title, maxFileNameLen.
+	char name[100];
+	// Unique identifier during a session.
+	int32_t uniqueId;
+	// File sample rate.
+	double sampleRate;
+	// Number of channels.
+	int32_t numChannels;
+	// Number of frames in the audio file.
+	double numFrames;
+	// Reserved for future use.
+	int32_t format;
+	// Edit cursor position, -1 if no such cursor.
+	double editCursorPosition;
+	// Frame index of first selected frame, or -1.
+	double selectionStart;
+	// Number of frames in selection, or 0.
+	double selectionSize;
+	// One bit per channel.
+	int32_t selectedChannelsMask;
+	// Number of markers in the file.
+	int32_t numMarkers;
+	// Time ruler unit.
+	int32_t timeRulerUnit;
+	// Offset in time ruler (positive or negative).
+	double timeRulerOffset;
+	// Tempo in BPM (Beats Per Minute).
+	double tempo;
+	// Time Signature Numerator (e.g. 3 for 3/4).
+	int32_t timeSigNumerator;
+	// Time Signature Denominator (e.g. 4 for 3/4).
+	int32_t timeSigDenominator;
+	// Resolution.
+	int32_t ticksPerBlackNote;
+	// SMPTEFrameRate value.
+	int32_t smpteFrameRate;
+
+	// Reserved for extension.
+	char future[64];
+} AudioFile;
+
+// OfflineTask describes an offline processing task.
+typedef struct OfflineTask
+{
+	// Set by plugin.
+	char processName[96];
+
+	// Set by host, position of the read head in source file.
+	double readPosition;
+	// Set by host, position of the write head in destination file.
+	double writePosition;
+	// Number of frames to read.
+	int32_t readCount;
+	// Number of frames to write.
+	int32_t writeCount;
+	// Size of input buffer in frames, set by host.
+	int32_t sizeInputBuffer;
+	// Size of output buffer in frames, set by host.
+	int32_t sizeOutputBuffer;
+	// Input buffer, float**, set by host.
+	void* inputBuffer;
+	// Output buffer, float**, set by host.
+	void* outputBuffer;
+	// Start position of processing.
+	double positionToProcessFrom;
+	// Number of frames to process.
+	double numFramesToProcess;
+	// Maximum number of frames to write, set by host.
+	double maxFramesToWrite;
+
+	// Extra buffer.
+	void* extraBuffer;
+	// Used by offline options.
+	int32_t value;
+	// Used by offline options.
+	int32_t index;
+
+	// Number of frames in source file.
+	double numFramesInSourceFile;
+	// Source sample rate.
+	double sourceSampleRate;
+	// Destination sample rate.
+	double destinationSampleRate;
+	// Number of source channels.
+	int32_t numSourceChannels;
+	// Number of destination channels.
+	int32_t numDestinationChannels;
+	// Reserved for future use.
+	int32_t sourceFormat;
+	// Reserved for future use.
+	int32_t destinationFormat;
+	// Text to display when processing is done.
+	char outputText[512];
+
+	// Progress value from 0 to 1.
+	double progress;
+	// Reserved for future use.
+	int32_t progressMode;
+	// Text to display while processing.
+	char progressText[100];
+
+	// OfflineTaskFlags values.
+	int32_t flags;
+	// Reserved for future use.
+	int32_t returnValue;
+	// Reserved for host.
+	void* hostOwned;
+	// Reserved for plugin.
+	void* plugOwned;
+
+	// Reserved for extension.
+	char future[1024];
+} OfflineTask;
+
+// VariableIO is used in variable I/O processing (offline e.g. timestretching).
+typedef struct VariableIO
+{
+	// Input audio buffers.
+	float** inputs;
+	// Output audio buffers.
+	float** outputs;
+	// Number of incoming samples.
+	int32_t numSamplesInput;
+	// Number of outgoing samples.
+	int32_t numSamplesOutput;
+	// Number of incoming samples processed by plugin.
+	int32_t* numSamplesInputProcessed;
+	// Number of outgoing samples produced by plugin.
+	int32_t* numSamplesOutputProcessed;
+} VariableIO;
+
 // Plugin's entry point
 typedef Effect* (*EntryPoint)(HostCallback host);
 
@@ -85,4 +225,10 @@ int64_t dispatch(Effect *effect, int opcode, int index, int64_t value, void *ptr
 void processDouble(Effect *effect, int numChannels, int blocksize, double **inputs, double **outputs);
 
 // Bridge to call process replacing function of loaded plugin
-void processFloat(Effect *effect, int numChannels, int blocksize, float **inputs, float **outputs);
\ No newline at end of file
+void processFloat(Effect *effect, int numChannels, int blocksize, float **inputs, float **outputs);
+
+// Bridge to call set parameter function of loaded plugin
+void setParameter(Effect *effect, int index, float value);
+
+// Bridge to call get parameter function of loaded plugin
+float getParameter(Effect *effect, int index);
\ No newline at end of file
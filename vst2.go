@@ -5,22 +5,33 @@ package vst2
 #cgo CFLAGS: -std=gnu99 -I${SRCDIR}
 #include <stdlib.h>
 #include <stdint.h>
+#include <string.h>
 #include "vst.h"
 */
 import "C"
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 )
 
-// global state for callbacks.
+// global state for callbacks. Callbacks are kept in copy-on-write
+// slice, so host callback doesn't lock. Index of the callback is stored
+// in user field of effect, zero index is never used.
 var (
-	mutex     sync.RWMutex
-	callbacks = make(map[*effect]HostCallbackFunc)
+	mutex     sync.Mutex
+	callbacks atomic.Value
+	// free are indexes of unregistered callbacks.
+	free []uintptr
 )
 
+func init() {
+	callbacks.Store([]HostCallbackFunc{nil})
+}
+
 //export hostCallback
 // global hostCallback, calls real callback.
 func hostCallback(e *effect, opcode int64, index int64, value int64, ptr unsafe.Pointer, opt float64) Return {
@@ -29,17 +40,74 @@ func hostCallback(e *effect, opcode int64, index int64, value int64, ptr unsafe.
 	if HostOpcode(opcode) == HostVersion {
 		return version
 	}
-	mutex.RLock()
-	c, ok := callbacks[e]
-	mutex.RUnlock()
-	if !ok {
+	cs := callbacks.Load().([]HostCallbackFunc)
+	i := e.callbackIndex()
+	if i == 0 || i >= uintptr(len(cs)) || cs[i] == nil {
 		panic("plugin was closed")
 	}
+	return cs[i](HostOpcode(opcode), Index(index), Value(value), Ptr(ptr), Opt(opt))
+}
 
-	if c == nil {
-		panic("host callback is undefined")
+// registerCallback stores callback, sets its index to effect and
+// returns it.
+func registerCallback(e *effect, c HostCallbackFunc) uintptr {
+	mutex.Lock()
+	defer mutex.Unlock()
+	cs := callbacks.Load().([]HostCallbackFunc)
+	updated := make([]HostCallbackFunc, len(cs), len(cs)+1)
+	copy(updated, cs)
+	var i uintptr
+	if n := len(free); n > 0 {
+		i = free[n-1]
+		free = free[:n-1]
+		updated[i] = c
+	} else {
+		i = uintptr(len(updated))
+		updated = append(updated, c)
+	}
+	callbacks.Store(updated)
+	e.setCallbackIndex(i)
+	return i
+}
+
+// unregisterCallback removes callback with index. Effect isn't
+// accessed, because plugin frees it on close.
+func unregisterCallback(i uintptr) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if i == 0 {
+		return
 	}
-	return c(HostOpcode(opcode), Index(index), Value(value), Ptr(ptr), Opt(opt))
+	cs := callbacks.Load().([]HostCallbackFunc)
+	updated := make([]HostCallbackFunc, len(cs))
+	copy(updated, cs)
+	updated[i] = nil
+	callbacks.Store(updated)
+	free = append(free, i)
+}
+
+// wrapCallback replaces callback with index by its wrapped version.
+func wrapCallback(i uintptr, wrap func(HostCallbackFunc) HostCallbackFunc) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if i == 0 {
+		return
+	}
+	cs := callbacks.Load().([]HostCallbackFunc)
+	updated := make([]HostCallbackFunc, len(cs))
+	copy(updated, cs)
+	updated[i] = wrap(cs[i])
+	callbacks.Store(updated)
+}
+
+// callbackIndex returns index of callback stored in user field. Field
+// is accessed as integer, because it doesn't contain a valid pointer.
+func (e *effect) callbackIndex() uintptr {
+	return *(*uintptr)(unsafe.Pointer(&e.user))
+}
+
+func (e *effect) setCallbackIndex(i uintptr) {
+	*(*uintptr)(unsafe.Pointer(&e.user)) = i
 }
 
 const (
@@ -76,8 +144,16 @@ type (
 
 	// VST used to create new instances of plugin.
 	// It also keeps reference to VST handle to clean up on Close.
+	// Handle is reference-counted: every loaded plugin holds a
+	// reference, so library is unloaded when VST and all its plugins
+	// are closed.
 	VST struct {
-		main effectMain
+		mutex sync.Mutex
+		// refs is a number of references: one is held until Close and
+		// one per open plugin.
+		refs   int
+		closed bool
+		main   effectMain
 		// handle is OS-specific.
 		handle
 		Name string
@@ -87,33 +163,101 @@ type (
 	// Plugin is VST2 plugin instance.
 	Plugin struct {
 		*effect
-		Name string
-		Path string
+		// vst is released when plugin is closed.
+		vst *VST
+		// callback is an index of registered host callback.
+		callback uintptr
+		// timeInfo is C-allocated, so it can be returned in HostGetTime.
+		timeInfo *TimeInfo
+		// arrangements are C-allocated and passed to plugin.
+		inArrangement  *speakerArrangement
+		outArrangement *speakerArrangement
+		state          PluginState
+		// AutoSuspend allows to change sample rate, buffer size and
+		// speaker arrangement of resumed plugin. It's suspended for the
+		// call and resumed after it.
+		AutoSuspend bool
+		Name        string
+		Path        string
 	}
+
+	// PluginState is a lifecycle state of plugin instance.
+	PluginState int
+)
+
+const (
+	// PluginClosed means that plugin is closed or not loaded.
+	PluginClosed PluginState = iota
+	// PluginSuspended means that plugin is opened and can be configured.
+	PluginSuspended
+	// PluginResumed means that plugin is resumed and can process audio.
+	PluginResumed
+	// PluginProcessing means that plugin is notified about process calls.
+	PluginProcessing
+)
+
+var pluginStates = [...]string{
+	PluginClosed:     "closed",
+	PluginSuspended:  "suspended",
+	PluginResumed:    "resumed",
+	PluginProcessing: "processing",
+}
+
+func (s PluginState) String() string {
+	if s < 0 || int(s) >= len(pluginStates) {
+		return fmt.Sprintf("PluginState(%d)", s)
+	}
+	return pluginStates[s]
+}
+
+var (
+	// ErrClosed is returned when closed plugin is used.
+	ErrClosed = errors.New("plugin is closed")
+	// ErrNotResumed is returned when suspended plugin is asked to process.
+	ErrNotResumed = errors.New("plugin is not resumed")
+	// ErrMustSuspend is returned when resumed plugin is configured.
+	ErrMustSuspend = errors.New("plugin must be suspended")
+	// ErrArrangementRejected is returned when plugin doesn't accept
+	// speaker arrangement.
+	ErrArrangementRejected = errors.New("speaker arrangement rejected")
 )
 
 // Open loads the VST into memory and stores entry point func.
-func Open(path string) (VST, error) {
+func Open(path string) (*VST, error) {
 	p, err := filepath.Abs(path)
 	if err != nil {
-		return VST{}, fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
 	m, h, err := open(p)
 	if err != nil {
-		return VST{}, fmt.Errorf("failed to load VST '%s': %w", path, err)
+		return nil, fmt.Errorf("failed to load VST '%s': %w", path, err)
 	}
 
-	return VST{
+	return &VST{
 		Path:   p,
+		refs:   1,
 		main:   m,
 		handle: h,
 	}, nil
 }
 
-// Close cleans up VST resoures.
-func (v VST) Close() error {
-	if v.main == nil {
+// Close releases VST reference. Library is unloaded when all plugins
+// loaded from it are closed. It's safe to call Close multiple times.
+func (v *VST) Close() error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.closed {
+		return nil
+	}
+	v.closed = true
+	return v.release()
+}
+
+// release decrements reference counter and unloads library when it
+// reaches zero. Must be called with mutex locked.
+func (v *VST) release() error {
+	if v.refs--; v.refs > 0 {
 		return nil
 	}
 	v.main = nil
@@ -125,42 +269,139 @@ func (v VST) Close() error {
 
 // Load new instance of VST plugin with provided callback.
 // This function also calls dispatch with EffOpen opcode.
-func (v VST) Load(c HostCallbackFunc) *Plugin {
-	if v.main == nil || c == nil {
+// Nil is returned if VST is closed.
+func (v *VST) Load(c HostCallbackFunc) *Plugin {
+	if c == nil {
+		return nil
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if v.closed || v.main == nil {
 		return nil
 	}
 	e := (*effect)(C.loadEffect(v.main))
-	mutex.Lock()
-	callbacks[e] = c
-	mutex.Unlock()
-
+	if e == nil {
+		return nil
+	}
+	v.refs++
 	p := &Plugin{
-		effect: e,
-		Path:   v.Path,
-		Name:   v.Name,
+		effect:   e,
+		vst:      v,
+		callback: registerCallback(e, c),
+		timeInfo: (*TimeInfo)(C.calloc(1, C.size_t(unsafe.Sizeof(TimeInfo{})))),
+		Path:     v.Path,
+		Name:     v.Name,
 	}
 	p.Dispatch(EffOpen, 0, 0, nil, 0.0)
 	return p
 }
 
-// Close cleans up C refs for plugin
+// Close cleans up C refs for plugin. Resumed plugin is suspended
+// before close. Callback is unregistered, so plugin must not call host
+// after that. If it's the last reference to closed VST, library is
+// unloaded. It's safe to call Close multiple times.
 func (p *Plugin) Close() error {
 	if p.effect == nil {
 		return nil
 	}
-	p.Dispatch(EffClose, 0, 0, nil, 0.0)
+	if p.state != PluginClosed {
+		p.Stop()
+		p.Dispatch(EffClose, 0, 0, nil, 0.0)
+	}
+	// effect is freed by plugin on close and must not be accessed.
 	p.effect = nil
-	return nil
+	unregisterCallback(p.callback)
+	p.callback = 0
+	C.free(unsafe.Pointer(p.timeInfo))
+	p.timeInfo = nil
+	p.inArrangement.free()
+	p.outArrangement.free()
+	p.inArrangement, p.outArrangement = nil, nil
+
+	v := p.vst
+	p.vst = nil
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	return v.release()
+}
+
+// TimeInfo returns C-allocated time info of the plugin. Host should
+// update it in place and return it as a result of HostGetTime call.
+// It's freed when plugin is closed.
+func (p *Plugin) TimeInfo() *TimeInfo {
+	return p.timeInfo
 }
 
-// Dispatch wraps-up C method to dispatch calls to plugin
+// State returns current lifecycle state of plugin.
+func (p *Plugin) State() PluginState {
+	return p.state
+}
+
+// Dispatch wraps-up C method to dispatch calls to plugin. Lifecycle
+// opcodes update the state of plugin. Calls to closed plugin are
+// ignored and zero is returned.
 func (p *Plugin) Dispatch(opcode EffectOpcode, index Index, value Value, ptr Ptr, opt Opt) Return {
-	return Return(C.dispatch((*C.Effect)(p.effect), C.int(opcode), C.int(index), C.int64_t(value), unsafe.Pointer(ptr), C.float(opt)))
+	if p.effect == nil || (p.state == PluginClosed && opcode != EffOpen) {
+		return 0
+	}
+	r := Return(C.dispatch((*C.Effect)(p.effect), C.int(opcode), C.int(index), C.int64_t(value), unsafe.Pointer(ptr), C.float(opt)))
+	switch opcode {
+	case EffOpen:
+		p.state = PluginSuspended
+	case EffClose:
+		p.state = PluginClosed
+	case EffStateChanged:
+		if value != 0 {
+			p.state = PluginResumed
+		} else {
+			p.state = PluginSuspended
+		}
+	case EffStartProcess:
+		p.state = PluginProcessing
+	case EffStopProcess:
+		p.state = PluginResumed
+	}
+	return r
+}
+
+// canProcess returns error if plugin is not ready to process audio.
+func (p *Plugin) canProcess() error {
+	switch p.state {
+	case PluginClosed:
+		return ErrClosed
+	case PluginSuspended:
+		return ErrNotResumed
+	}
+	return nil
+}
+
+// suspended calls fn if plugin is suspended. If plugin is resumed and
+// AutoSuspend is set, plugin is suspended for the call and then brought
+// back to its previous state.
+func (p *Plugin) suspended(fn func()) error {
+	switch p.state {
+	case PluginClosed:
+		return ErrClosed
+	case PluginSuspended:
+		fn()
+		return nil
+	}
+	if !p.AutoSuspend {
+		return fmt.Errorf("%w: plugin is %v", ErrMustSuspend, p.state)
+	}
+	state := p.state
+	p.Stop()
+	fn()
+	p.Start()
+	if state == PluginProcessing {
+		p.StartProcess()
+	}
+	return nil
 }
 
 // CanProcessFloat32 checks if plugin can process float32.
 func (p *Plugin) CanProcessFloat32() bool {
-	if p == nil {
+	if p == nil || p.effect == nil {
 		return false
 	}
 	return EffectFlags(p.effect.flags)&EffFlagsCanReplacing == EffFlagsCanReplacing
@@ -168,93 +409,470 @@ func (p *Plugin) CanProcessFloat32() bool {
 
 // CanProcessFloat64 checks if plugin can process float64.
 func (p *Plugin) CanProcessFloat64() bool {
-	if p == nil {
+	if p == nil || p.effect == nil {
 		return false
 	}
 	return EffectFlags(p.effect.flags)&EffFlagsCanDoubleReplacing == EffFlagsCanDoubleReplacing
 }
 
-// ProcessDouble audio with VST plugin.
-func (p *Plugin) ProcessDouble(in, out DoubleBuffer) {
+// Flags returns plugin flags.
+func (p *Plugin) Flags() EffectFlags {
+	if p.effect == nil {
+		return 0
+	}
+	return EffectFlags(p.effect.flags)
+}
+
+// UniqueID returns registered unique identifier of plugin.
+func (p *Plugin) UniqueID() int32 {
+	if p.effect == nil {
+		return 0
+	}
+	return int32(p.effect.uniqueID)
+}
+
+// InitialDelay returns latency of plugin in samples. It's valid when
+// plugin is resumed.
+func (p *Plugin) InitialDelay() int {
+	if p.effect == nil {
+		return 0
+	}
+	return int(p.effect.initialDelay)
+}
+
+// NumParams returns number of plugin parameters.
+func (p *Plugin) NumParams() int {
+	if p.effect == nil {
+		return 0
+	}
+	return int(p.effect.numParams)
+}
+
+// NumPrograms returns number of plugin programs.
+func (p *Plugin) NumPrograms() int {
+	if p.effect == nil {
+		return 0
+	}
+	return int(p.effect.numPrograms)
+}
+
+// Chunk returns a copy of plugin state chunk. If program is true, only
+// current program is returned, whole bank otherwise. Nil is returned
+// if plugin doesn't support chunks.
+func (p *Plugin) Chunk(program bool) []byte {
+	if p.Flags()&EffFlagsProgramChunks == 0 {
+		return nil
+	}
+	// plugin sets pointer to its own memory.
+	ptr := (*unsafe.Pointer)(C.calloc(1, C.size_t(unsafe.Sizeof(uintptr(0)))))
+	defer C.free(unsafe.Pointer(ptr))
+	size := p.Dispatch(EffGetChunk, chunkIndex(program), 0, Ptr(unsafe.Pointer(ptr)), 0)
+	if size <= 0 || *ptr == nil {
+		return nil
+	}
+	return C.GoBytes(*ptr, C.int(size))
+}
+
+// SetChunk passes state chunk to plugin. If program is true, chunk
+// contains only current program, whole bank otherwise.
+func (p *Plugin) SetChunk(data []byte, program bool) error {
+	if p.state == PluginClosed {
+		return ErrClosed
+	}
+	if p.Flags()&EffFlagsProgramChunks == 0 {
+		return fmt.Errorf("plugin %s doesn't support chunks", p.Name)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	chunk := C.CBytes(data)
+	defer C.free(chunk)
+	p.Dispatch(EffSetChunk, chunkIndex(program), Value(len(data)), Ptr(chunk), 0)
+	return nil
+}
+
+func chunkIndex(program bool) Index {
+	if program {
+		return 1
+	}
+	return 0
+}
+
+// Version returns version of plugin, e.g. 1100 for 1.1.0.0.
+func (p *Plugin) Version() int32 {
+	if p.effect == nil {
+		return 0
+	}
+	return int32(p.effect.version)
+}
+
+// NumInputs returns number of plugin inputs.
+func (p *Plugin) NumInputs() int {
+	if p.effect == nil {
+		return 0
+	}
+	return int(p.effect.numInputs)
+}
+
+// NumOutputs returns number of plugin outputs.
+func (p *Plugin) NumOutputs() int {
+	if p.effect == nil {
+		return 0
+	}
+	return int(p.effect.numOutputs)
+}
+
+// EffectName returns name of the effect.
+func (p *Plugin) EffectName() string {
+	return p.dispatchString(EffGetEffectName, 0)
+}
+
+// Vendor returns name of plugin vendor.
+func (p *Plugin) Vendor() string {
+	return p.dispatchString(EffGetVendorString, 0)
+}
+
+// Product returns name of plugin product.
+func (p *Plugin) Product() string {
+	return p.dispatchString(EffGetProductString, 0)
+}
+
+// VendorVersion returns vendor-specific version of plugin.
+func (p *Plugin) VendorVersion() int {
+	return int(p.Dispatch(EffGetVendorVersion, 0, 0, nil, 0))
+}
+
+// VSTVersion returns version of VST SDK that plugin implements, e.g.
+// 2400 for VST 2.4.
+func (p *Plugin) VSTVersion() int {
+	return int(p.Dispatch(EffGetVstVersion, 0, 0, nil, 0))
+}
+
+// Category returns category of plugin.
+func (p *Plugin) Category() PluginCategory {
+	return PluginCategory(p.Dispatch(EffGetPlugCategory, 0, 0, nil, 0))
+}
+
+// CanDo checks if plugin supports capability.
+func (p *Plugin) CanDo(c PluginCanDo) CanDoResponse {
+	if p.state == PluginClosed {
+		return CanDoMaybe
+	}
+	s := C.CString(string(c))
+	defer C.free(unsafe.Pointer(s))
+	switch r := p.Dispatch(EffCanDo, 0, 0, Ptr(unsafe.Pointer(s)), 0); {
+	case r > 0:
+		return CanDoYes
+	case r < 0:
+		return CanDoNo
+	default:
+		return CanDoMaybe
+	}
+}
+
+// ParamName returns name of parameter, e.g. "Gain".
+func (p *Plugin) ParamName(index int) string {
+	return p.dispatchString(EffGetParamName, Index(index))
+}
+
+// ParamLabel returns unit label of parameter, e.g. "dB".
+func (p *Plugin) ParamLabel(index int) string {
+	return p.dispatchString(EffGetParamLabel, Index(index))
+}
+
+// ParamDisplay returns current value of parameter as text, e.g. "-6.0".
+func (p *Plugin) ParamDisplay(index int) string {
+	return p.dispatchString(EffGetParamDisplay, Index(index))
+}
+
+// ProgramNameIndexed returns name of program without changing current
+// program.
+func (p *Plugin) ProgramNameIndexed(index int) string {
+	return p.dispatchString(EffGetProgramNameIndexed, Index(index))
+}
+
+// Program returns index of current program.
+func (p *Plugin) Program() int {
+	return int(p.Dispatch(EffGetProgram, 0, 0, nil, 0))
+}
+
+// SetProgram sets current program.
+func (p *Plugin) SetProgram(index int) {
+	p.Dispatch(EffSetProgram, 0, Value(index), nil, 0)
+}
+
+// ProgramName returns name of current program.
+func (p *Plugin) ProgramName() string {
+	return p.dispatchString(EffGetProgramName, 0)
+}
+
+// SetProgramName sets name of current program. Name is truncated to
+// 24 characters.
+func (p *Plugin) SetProgramName(name string) {
+	if p.state == PluginClosed {
+		return
+	}
+	if len(name) > maxProgNameLen {
+		name = name[:maxProgNameLen]
+	}
+	s := C.CString(name)
+	defer C.free(unsafe.Pointer(s))
+	p.Dispatch(EffSetProgramName, 0, 0, Ptr(unsafe.Pointer(s)), 0)
+}
+
+// TailSize returns number of samples plugin produces after input
+// stops. Zero means that plugin doesn't report tail, one means that
+// plugin has no tail.
+func (p *Plugin) TailSize() int {
+	return int(p.Dispatch(EffGetTailSize, 0, 0, nil, 0))
+}
+
+// maxStringLen is a size of buffers for strings returned by plugin.
+// Plugins often ignore limits of SDK, so buffers are larger.
+const maxStringLen = 256
+
+// dispatchString returns string that plugin writes into buffer.
+func (p *Plugin) dispatchString(opcode EffectOpcode, index Index) string {
+	if p.state == PluginClosed {
+		return ""
+	}
+	buf := (*C.char)(C.calloc(maxStringLen, 1))
+	defer C.free(unsafe.Pointer(buf))
+	p.Dispatch(opcode, index, 0, Ptr(unsafe.Pointer(buf)), 0)
+	return C.GoStringN(buf, C.int(C.strnlen(buf, maxStringLen-1)))
+}
+
+// SetBypass tells plugin to bypass processing. False is returned if
+// plugin doesn't support soft bypass, host should bypass it then.
+func (p *Plugin) SetBypass(bypass bool) bool {
+	var v Value
+	if bypass {
+		v = 1
+	}
+	return p.Dispatch(EffSetBypass, 0, v, nil, 0) != 0
+}
+
+// ProcessDouble audio with VST plugin. Plugin must be resumed.
+func (p *Plugin) ProcessDouble(in, out DoubleBuffer) error {
+	if err := p.canProcess(); err != nil {
+		return err
+	}
 	C.processDouble(
 		(*C.Effect)(p.effect),
 		C.int(in.numChannels),
 		C.int(in.size),
-		&in.data[0],
-		&out.data[0],
+		in.ptr(),
+		out.ptr(),
 	)
+	return nil
 }
 
-// ProcessFloat audio with VST plugin.
-func (p *Plugin) ProcessFloat(in, out FloatBuffer) {
+// ProcessFloat audio with VST plugin. Plugin must be resumed.
+func (p *Plugin) ProcessFloat(in, out FloatBuffer) error {
+	if err := p.canProcess(); err != nil {
+		return err
+	}
 	C.processFloat(
 		(*C.Effect)(p.effect),
 		C.int(in.numChannels),
 		C.int(in.size),
-		&in.data[0],
-		&out.data[0],
+		in.ptr(),
+		out.ptr(),
 	)
+	return nil
+}
+
+// SetParameter sets new value for parameter.
+func (p *Plugin) SetParameter(index int, value float32) error {
+	if p.state == PluginClosed {
+		return ErrClosed
+	}
+	C.setParameter((*C.Effect)(p.effect), C.int(index), C.float(value))
+	return nil
 }
 
-// Start the plugin.
-func (p *Plugin) Start() {
-	p.Dispatch(EffStateChanged, 0, 1, nil, 0.0)
+// Parameter returns current value of parameter. Zero is returned if
+// plugin is closed.
+func (p *Plugin) Parameter(index int) float32 {
+	if p.state == PluginClosed {
+		return 0
+	}
+	return float32(C.getParameter((*C.Effect)(p.effect), C.int(index)))
 }
 
-// Stop the plugin.
-func (p *Plugin) Stop() {
-	p.Dispatch(EffStateChanged, 0, 0, nil, 0.0)
+// Start resumes the plugin. It's no-op if plugin is already resumed.
+func (p *Plugin) Start() error {
+	switch p.state {
+	case PluginClosed:
+		return ErrClosed
+	case PluginSuspended:
+		p.Dispatch(EffStateChanged, 0, 1, nil, 0.0)
+	}
+	return nil
 }
 
-// SetBufferSize sets a buffer size
-func (p *Plugin) SetBufferSize(bufferSize int) {
-	p.Dispatch(EffSetBufferSize, 0, Value(bufferSize), nil, 0.0)
+// Stop suspends the plugin. If plugin is processing, it's notified that
+// process calls are stopped first.
+func (p *Plugin) Stop() error {
+	switch p.state {
+	case PluginClosed:
+		return ErrClosed
+	case PluginProcessing:
+		p.Dispatch(EffStopProcess, 0, 0, nil, 0.0)
+		fallthrough
+	case PluginResumed:
+		p.Dispatch(EffStateChanged, 0, 0, nil, 0.0)
+	}
+	return nil
 }
 
-// SetSampleRate sets a sample rate for plugin
-func (p *Plugin) SetSampleRate(sampleRate int) {
-	p.Dispatch(EffSetSampleRate, 0, 0, nil, Opt(sampleRate))
+// StartProcess notifies plugin that process calls will follow. Plugin
+// must be resumed.
+func (p *Plugin) StartProcess() error {
+	switch p.state {
+	case PluginClosed:
+		return ErrClosed
+	case PluginSuspended:
+		return ErrNotResumed
+	case PluginResumed:
+		p.Dispatch(EffStartProcess, 0, 0, nil, 0.0)
+	}
+	return nil
 }
 
-// SetSpeakerArrangement craetes and passes SpeakerArrangement structures to plugin
-func (p *Plugin) SetSpeakerArrangement(in, out *SpeakerArrangement) {
-	p.Dispatch(EffSetSpeakerArrangement, 0, in.Value(), out.Ptr(), 0.0)
+// StopProcess notifies plugin that process calls are stopped.
+func (p *Plugin) StopProcess() error {
+	switch p.state {
+	case PluginClosed:
+		return ErrClosed
+	case PluginProcessing:
+		p.Dispatch(EffStopProcess, 0, 0, nil, 0.0)
+	}
+	return nil
 }
 
-// ScanPaths returns a slice of default vst2 locations.
-// Locations are OS-specific.
-func ScanPaths() (paths []string) {
-	return append([]string{}, scanPaths...)
+// SetTotalSamplesToProcess tells plugin the number of samples that
+// will be processed in offline mode.
+func (p *Plugin) SetTotalSamplesToProcess(numSamples int) {
+	p.Dispatch(EffSetTotalSampleToProcess, 0, Value(numSamples), nil, 0.0)
 }
 
-func newSpeakerArrangement(numChannels int) *SpeakerArrangement {
-	sa := SpeakerArrangement{}
-	sa.NumChannels = int32(numChannels)
-	switch numChannels {
-	case 0:
-		sa.Type = SpeakerArrEmpty
-	case 1:
-		sa.Type = SpeakerArrMono
-	case 2:
-		sa.Type = SpeakerArrStereo
-	case 3:
-		sa.Type = SpeakerArr30Music
-	case 4:
-		sa.Type = SpeakerArr40Music
-	case 5:
-		sa.Type = SpeakerArr50
-	case 6:
-		sa.Type = SpeakerArr60Music
-	case 7:
-		sa.Type = SpeakerArr70Music
-	case 8:
-		sa.Type = SpeakerArr80Music
-	default:
-		sa.Type = SpeakerArrUserDefined
+// SetPanLaw sets pan law type and gain.
+func (p *Plugin) SetPanLaw(law PanLawType, gain float32) {
+	p.Dispatch(EffSetPanLaw, 0, Value(law), nil, Opt(gain))
+}
+
+// SetBufferSize sets a buffer size. Plugin must be suspended.
+func (p *Plugin) SetBufferSize(bufferSize int) error {
+	return p.suspended(func() {
+		p.Dispatch(EffSetBufferSize, 0, Value(bufferSize), nil, 0.0)
+	})
+}
+
+// SetSampleRate sets a sample rate for plugin. Plugin must be suspended.
+func (p *Plugin) SetSampleRate(sampleRate int) error {
+	return p.suspended(func() {
+		p.Dispatch(EffSetSampleRate, 0, 0, nil, Opt(sampleRate))
+	})
+}
+
+// SetSpeakerArrangement craetes and passes SpeakerArrangement structures to plugin.
+// C structures are kept until arrangement is set again or plugin is closed.
+// Plugin must be suspended. ErrArrangementRejected is returned if plugin
+// doesn't accept arrangements.
+func (p *Plugin) SetSpeakerArrangement(in, out *SpeakerArrangement) error {
+	var accepted bool
+	err := p.suspended(func() {
+		p.inArrangement.free()
+		p.outArrangement.free()
+		p.inArrangement = in.alloc()
+		p.outArrangement = out.alloc()
+		accepted = p.Dispatch(EffSetSpeakerArrangement, 0, p.inArrangement.Value(), p.outArrangement.Ptr(), 0.0) != 0
+	})
+	if err != nil {
+		return err
 	}
+	if !accepted {
+		return ErrArrangementRejected
+	}
+	return nil
+}
 
-	for i := 0; i < int(numChannels); i++ {
-		sa.Speakers[i].Type = SpeakerUndefined
+// SpeakerArrangement returns copies of plugin's preferred input and
+// output arrangements. Nil values are returned if plugin doesn't
+// provide them.
+func (p *Plugin) SpeakerArrangement() (in, out *SpeakerArrangement) {
+	// plugin sets pointers to its own arrangements.
+	ptrs := (*[2]*speakerArrangement)(C.calloc(2, C.size_t(unsafe.Sizeof(uintptr(0)))))
+	defer C.free(unsafe.Pointer(ptrs))
+	if p.Dispatch(EffGetSpeakerArrangement, 0, Value(uintptr(unsafe.Pointer(&ptrs[0]))), Ptr(unsafe.Pointer(&ptrs[1])), 0) == 0 {
+		return nil, nil
 	}
-	return &sa
+	return ptrs[0].arrangement(), ptrs[1].arrangement()
+}
+
+// NegotiateSpeakerArrangement sets the first candidate accepted by
+// plugin. If plugin rejects all candidates, its preferred arrangement is
+// tried. Accepted config is returned. ErrArrangementRejected is returned
+// if plugin accepted none of them.
+func (p *Plugin) NegotiateSpeakerArrangement(candidates ...SpeakerConfig) (SpeakerConfig, error) {
+	for _, c := range candidates {
+		if err := p.SetSpeakerArrangement(c.In, c.Out); !errors.Is(err, ErrArrangementRejected) {
+			return c, err
+		}
+	}
+	in, out := p.SpeakerArrangement()
+	if in == nil || out == nil {
+		return SpeakerConfig{}, ErrArrangementRejected
+	}
+	if err := p.SetSpeakerArrangement(in, out); err != nil {
+		return SpeakerConfig{}, err
+	}
+	return SpeakerConfig{In: in, Out: out}, nil
+}
+
+// InputPins returns properties of plugin inputs. If plugin doesn't
+// provide properties, they have empty labels and only PinIsActive flag.
+func (p *Plugin) InputPins() []PinProperties {
+	if p.effect == nil {
+		return nil
+	}
+	return p.pins(EffGetInputProperties, int(p.effect.numInputs))
+}
+
+// OutputPins returns properties of plugin outputs. If plugin doesn't
+// provide properties, they have empty labels and only PinIsActive flag.
+func (p *Plugin) OutputPins() []PinProperties {
+	if p.effect == nil {
+		return nil
+	}
+	return p.pins(EffGetOutputProperties, int(p.effect.numOutputs))
+}
+
+func (p *Plugin) pins(opcode EffectOpcode, numPins int) []PinProperties {
+	if p.state == PluginClosed {
+		return nil
+	}
+	pins := make([]PinProperties, numPins)
+	var pp pinProperties
+	for i := range pins {
+		pp = pinProperties{}
+		if p.Dispatch(opcode, Index(i), 0, pp.Ptr(), 0) == 0 {
+			pins[i] = PinProperties{Flags: PinIsActive}
+			continue
+		}
+		pins[i] = PinProperties{
+			Label:           goString(pp.label[:]),
+			ShortLabel:      goString(pp.shortLabel[:]),
+			Flags:           pp.flags,
+			ArrangementType: pp.arrangementType,
+		}
+	}
+	return pins
+}
+
+// ScanPaths returns a slice of default vst2 locations.
+// Locations are OS-specific.
+func ScanPaths() (paths []string) {
+	return append([]string{}, scanPaths...)
 }
@@ -1,6 +1,7 @@
 package vst2_test
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 	"testing"
@@ -122,3 +123,46 @@ func zeroesFloat32(nums []float32) (count int, proportion float64, positions []i
 	proportion = float64(100*count) / float64(len(nums))
 	return
 }
+
+func TestPluginState(t *testing.T) {
+	vst, err := vst2.Open(pluginPath)
+	require.Nil(t, err)
+	defer vst.Close()
+
+	p := vst.Load(testHostCallback())
+	assert.Equal(t, vst2.PluginSuspended, p.State())
+
+	in := vst2.NewDoubleBuffer(samples64.NumChannels(), samples64.Size())
+	out := vst2.NewDoubleBuffer(samples64.NumChannels(), samples64.Size())
+	defer in.Free()
+	defer out.Free()
+	assert.True(t, errors.Is(p.ProcessDouble(in, out), vst2.ErrNotResumed))
+
+	require.Nil(t, p.Start())
+	assert.True(t, errors.Is(p.SetSampleRate(sampleRate), vst2.ErrMustSuspend))
+	stereo := vst2.DefaultSpeakerArrangement(2)
+	assert.True(t, errors.Is(p.SetSpeakerArrangement(stereo, stereo), vst2.ErrMustSuspend))
+	p.AutoSuspend = true
+	require.Nil(t, p.StartProcess())
+	assert.Nil(t, p.SetSampleRate(sampleRate))
+	assert.Equal(t, vst2.PluginProcessing, p.State())
+	assert.Nil(t, p.ProcessDouble(in, out))
+
+	// library stays loaded until plugin is closed.
+	require.Nil(t, vst.Close())
+	assert.Nil(t, vst.Load(testHostCallback()))
+	assert.Nil(t, p.ProcessDouble(in, out))
+	require.Nil(t, p.Close())
+	assert.Nil(t, p.Close())
+	assert.Equal(t, vst2.PluginClosed, p.State())
+	assert.True(t, errors.Is(p.ProcessDouble(in, out), vst2.ErrClosed))
+	assert.True(t, errors.Is(p.SetBufferSize(64), vst2.ErrClosed))
+}
+
+func TestEffectFlags(t *testing.T) {
+	flags := vst2.EffFlagsCanReplacing | vst2.EffFlagsIsSynth
+	assert.Equal(t, []string{"CanReplacing", "IsSynth"}, flags.Names())
+	assert.Equal(t, "CanReplacing|IsSynth", flags.String())
+	assert.Equal(t, "RoomFx", vst2.PluginCategoryRoomFx.String())
+	assert.Equal(t, "PluginCategory(42)", vst2.PluginCategory(42).String())
+}